@@ -17,7 +17,7 @@ limitations under the License.
 // Package main provides a dummy plugin for csctl. You can use that code
 // to create a real csctl plugin.
 // You can implement the "create-node-images" command to create node images during
-// a `csctl create` call.
+// a `csctl create` call. The "version" command returns the protocol version the plugin implements.
 package main
 
 import (
@@ -25,12 +25,19 @@ import (
 	"os"
 
 	csctlclusterstack "github.com/SovereignCloudStack/csctl/pkg/clusterstack"
+	"github.com/SovereignCloudStack/csctl/pkg/pluginprotocol"
 )
 
-const provider = "docker"
+const (
+	provider = "docker"
+
+	// providerAPIVersion is the version of the provider config in csctl.yaml that this plugin understands.
+	providerAPIVersion = "docker.csctl.clusterstack.x-k8s.io/v1alpha1"
+)
 
 func usage() {
-	fmt.Printf(`%s create-node-images cluster-stack-directory cluster-stack-release-directory
+	fmt.Printf(`%[1]s create-node-images cluster-stack-directory cluster-stack-release-directory node-image-registry [protocol-version]
+%[1]s version
 This command is a csctl plugin.
 
 https://github.com/SovereignCloudStack/csctl
@@ -38,36 +45,67 @@ https://github.com/SovereignCloudStack/csctl
 }
 
 func main() {
-	numArgs := 5
-	if len(os.Args) != numArgs {
-		fmt.Printf("Wrong number of arguments. Expected %d got %d\n", numArgs, len(os.Args))
+	if len(os.Args) == 2 && os.Args[1] == pluginprotocol.CommandVersion {
+		if err := pluginprotocol.WriteVersion(os.Stdout); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	// The protocol version is passed as additional argument by newer versions of csctl.
+	if len(os.Args) != 5 && len(os.Args) != 6 {
+		fmt.Printf("Wrong number of arguments. Expected 5 or 6 got %d\n", len(os.Args))
 		usage()
 		os.Exit(1)
 	}
-	if os.Args[1] != "create-node-images" {
+	if os.Args[1] != pluginprotocol.CommandCreateNodeImages {
 		usage()
 		os.Exit(1)
 	}
-	clusterStackPath := os.Args[2]
-	config, err := csctlclusterstack.GetCsctlConfig(clusterStackPath)
-	if err != nil {
-		fmt.Println(err.Error())
-		os.Exit(1)
+
+	var (
+		config            *csctlclusterstack.CsctlConfig
+		clusterStackPath  = os.Args[2]
+		releaseDir        = os.Args[3]
+		nodeImageRegistry = os.Args[4]
+		err               error
+	)
+
+	if len(os.Args) == 6 && os.Args[5] == pluginprotocol.ProtocolVersion {
+		envelope, err := pluginprotocol.ReadEnvelope(os.Stdin)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		if err := envelope.CheckProviderAPIVersion(providerAPIVersion); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		config = envelope.Config
+		clusterStackPath = envelope.ClusterStackPath
+		releaseDir = envelope.ReleaseDir
+		nodeImageRegistry = envelope.NodeImageRegistry
+	} else {
+		config, err = csctlclusterstack.GetCsctlConfig(clusterStackPath)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
 	}
+
 	if config.Config.Provider.Type != provider {
 		fmt.Printf("Wrong provider in %s. Expected %s\n", clusterStackPath, provider)
 		os.Exit(1)
 	}
-	releaseDir := os.Args[3]
 	_, err = os.Stat(releaseDir)
 	if err != nil {
 		fmt.Println(err.Error())
 		os.Exit(1)
 	}
-	nodeImageRegistry := os.Args[4]
 	fmt.Printf("clusterStackPath: %s\n", clusterStackPath)
 	fmt.Printf("releaseDir: %s\n", releaseDir)
 	fmt.Printf("nodeImageRegistry: %s\n", nodeImageRegistry)
-	fmt.Printf("..... pretending to read config: %s\n", config.Config.Provider.Config["dummyKey"])
+	fmt.Printf("..... pretending to read config: %s\n", config.Config.Provider.Config.Extra["dummyKey"])
 	fmt.Printf("..... pretending to do heavy work (creating node images) ...\n")
 }
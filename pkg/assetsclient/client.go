@@ -19,8 +19,29 @@ package assetsclient
 
 import (
 	"context"
+	"net/http"
+	"net/url"
 )
 
+// Options configures the HTTP connections of asset clients.
+type Options struct {
+	// Proxy is the URL of the proxy. If it is nil, the proxy is read from the environment
+	// variables HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+	Proxy *url.URL
+}
+
+// NewTransport returns the HTTP transport used by asset clients. It uses the proxy of the options or the proxy
+// from the environment.
+func (o Options) NewTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if o.Proxy != nil {
+		transport.Proxy = http.ProxyURL(o.Proxy)
+	}
+
+	return transport
+}
+
 // Client contains functions to talk to list and download assets.
 type Client interface {
 	DownloadReleaseAssets(ctx context.Context, tag, path string) error
@@ -33,8 +54,56 @@ type Factory interface {
 }
 
 // Pusher contains function to push the release assets to the registry.
+// PushReleaseAssets returns the digest of the pushed release, or an empty string if the registry has no digests.
+// FoundRelease returns an error if it cannot be determined whether the release exists, e.g. because of a network error.
 type Pusher interface {
-	PushReleaseAssets(ctx context.Context, releaseAssets []ReleaseAsset, tag, dir, artifactType string, metadata map[string]string) error
+	PushReleaseAssets(ctx context.Context, releaseAssets []ReleaseAsset, tag, dir, artifactType string, metadata map[string]string) (string, error)
+	FoundRelease(ctx context.Context, tag string) (bool, error)
+}
+
+// Deleter contains function to delete releases from the registry.
+type Deleter interface {
+	DeleteRelease(ctx context.Context, tag string) error
+}
+
+// Tagger contains function to add another tag to an existing release.
+type Tagger interface {
+	TagRelease(ctx context.Context, tag, alias string) error
+}
+
+// Locator contains function to describe where releases are published, e.g. to confirm publishing.
+type Locator interface {
+	Location() string
+}
+
+// Planner contains function to compute the digest of a release before it is pushed.
+// It returns the same digest as PushReleaseAssets for the same arguments.
+type Planner interface {
+	ReleaseDigest(ctx context.Context, releaseAssets []ReleaseAsset, dir, artifactType string, metadata map[string]string) (string, error)
+}
+
+// MetadataGetter contains functions to inspect a release without downloading all release assets.
+type MetadataGetter interface {
+	GetReleaseMetadata(ctx context.Context, tag string) (map[string]string, error)
+	GetReleaseArtifactType(ctx context.Context, tag string) (string, error)
+	GetReleaseFile(ctx context.Context, tag, fileName string, maxSize int64) ([]byte, error)
+	// ListReleaseWithMetadata returns the releases for which match returns true, or all releases if match is nil.
+	// Errors of single releases don't abort the listing, they are returned in ReleaseWithMetadata.Err.
+	ListReleaseWithMetadata(ctx context.Context, match func(tag string) bool) ([]ReleaseWithMetadata, error)
+}
+
+// ReleaseWithMetadata is a release together with the annotations of its manifest.
+type ReleaseWithMetadata struct {
+	Tag         string
+	Annotations map[string]string
+	// Err is the error of getting the annotations of the release, if any.
+	Err error
+}
+
+// ReleaseIdentifier contains function to identify the content of a release, e.g. to cache it.
+// The ID changes if the content of the release changes.
+type ReleaseIdentifier interface {
+	ReleaseID(ctx context.Context, tag string) (string, error)
 }
 
 // ReleaseAsset represents a release asset that would together make up the artifact.
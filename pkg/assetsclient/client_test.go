@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package assetsclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// newTestProxy starts a proxy that answers all requests itself and records the requested URLs.
+func newTestProxy(t *testing.T) (*url.URL, *[]string) {
+	t.Helper()
+
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.String())
+		_, _ = w.Write([]byte("proxied"))
+	}))
+	t.Cleanup(server.Close)
+
+	proxyURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return proxyURL, &requests
+}
+
+func TestNewTransportProxy(t *testing.T) {
+	proxyURL, requests := newTestProxy(t)
+
+	client := &http.Client{Transport: Options{Proxy: proxyURL}.NewTransport()}
+
+	resp, err := client.Get("http://registry.example.com/v2/")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(body) != "proxied" {
+		t.Errorf("got response %q, want it from the proxy", body)
+	}
+	if len(*requests) != 1 || (*requests)[0] != "http://registry.example.com/v2/" {
+		t.Errorf("proxy got requests %v, want http://registry.example.com/v2/", *requests)
+	}
+}
+
+// TestNewTransportProxyFromEnvironment is the only test that reads the proxy from the environment, because
+// http.ProxyFromEnvironment reads the environment only once.
+func TestNewTransportProxyFromEnvironment(t *testing.T) {
+	proxyURL, _ := newTestProxy(t)
+
+	t.Setenv("HTTP_PROXY", proxyURL.String())
+	t.Setenv("HTTPS_PROXY", proxyURL.String())
+	t.Setenv("NO_PROXY", "internal.example.com")
+
+	transport := Options{}.NewTransport()
+
+	tests := []struct {
+		url       string
+		wantProxy bool
+	}{
+		{url: "https://ghcr.io/v2/", wantProxy: true},
+		{url: "https://api.github.com/repos/org/repo/releases", wantProxy: true},
+		{url: "https://internal.example.com/v2/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.url, http.NoBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := transport.Proxy(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantProxy && (got == nil || got.String() != proxyURL.String()) {
+				t.Errorf("proxy of %s = %v, want %s", tt.url, got, proxyURL)
+			}
+			if !tt.wantProxy && got != nil {
+				t.Errorf("proxy of %s = %s, want none", tt.url, got)
+			}
+		})
+	}
+}
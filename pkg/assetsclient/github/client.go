@@ -19,13 +19,17 @@ package github
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/SovereignCloudStack/csctl/pkg/assetsclient"
+	"github.com/SovereignCloudStack/csctl/pkg/logging"
 	"github.com/google/go-github/v56/github"
 	"golang.org/x/oauth2"
 )
@@ -35,33 +39,65 @@ type realGhClient struct {
 	httpclient *http.Client
 	orgName    string
 	repoName   string
+	assetNames []string
 }
 
-type factory struct{}
+type factory struct {
+	options    assetsclient.Options
+	assetNames []string
+}
 
 var _ = assetsclient.Client(&realGhClient{})
 
+var _ = assetsclient.Pusher(&realGhClient{})
+
 var _ = assetsclient.Factory(&factory{})
 
+var _ = assetsclient.ReleaseIdentifier(&realGhClient{})
+
 // NewFactory returns a new factory for Github clients.
-func NewFactory() assetsclient.Factory {
-	return &factory{}
+func NewFactory(opts assetsclient.Options) assetsclient.Factory {
+	return &factory{options: opts}
 }
 
-var _ = assetsclient.Client(&realGhClient{})
+// NewFactoryForAssets returns a new factory for Github clients that only download the release assets
+// with the given names. If no names are given, all release assets are downloaded.
+func NewFactoryForAssets(opts assetsclient.Options, assetNames ...string) assetsclient.Factory {
+	return &factory{options: opts, assetNames: assetNames}
+}
+
+func (f *factory) NewClient(ctx context.Context) (assetsclient.Client, error) {
+	client, err := newClient(ctx, f.options)
+	if err != nil {
+		return nil, err
+	}
+
+	client.assetNames = f.assetNames
+	return client, nil
+}
 
-func (*factory) NewClient(ctx context.Context) (assetsclient.Client, error) {
+// NewPusher returns a new Github client which is able to push release assets.
+func NewPusher(ctx context.Context, opts assetsclient.Options) (assetsclient.Pusher, error) {
+	client, err := newClient(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func newClient(ctx context.Context, opts assetsclient.Options) (*realGhClient, error) {
 	creds, err := NewGitConfig()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create git config: %w", err)
 	}
-	ghclient, oAuthClient, err := githubAndOAuthClientWithToken(ctx, creds)
+	ghclient, oAuthClient, err := githubAndOAuthClientWithToken(ctx, creds, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create github client: %w", err)
 	}
 
 	if oAuthClient == nil {
-		oAuthClient = http.DefaultClient
+		oAuthClient = &http.Client{Transport: opts.NewTransport()}
 	}
 
 	return &realGhClient{
@@ -72,23 +108,130 @@ func (*factory) NewClient(ctx context.Context) (assetsclient.Client, error) {
 	}, nil
 }
 
+// ListRelease returns the names of all releases. All pages of the Github API are fetched.
 func (c *realGhClient) ListRelease(ctx context.Context) ([]string, error) {
-	repoRelease, response, err := c.client.Repositories.ListReleases(ctx, c.orgName, c.repoName, &github.ListOptions{})
+	releases := []string{}
+
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		repoRelease, response, err := c.client.Repositories.ListReleases(ctx, c.orgName, c.repoName, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list releases: %w", err)
+		}
+
+		if response != nil && response.StatusCode != 200 {
+			return nil, fmt.Errorf("got unexpected status from call to remote repository: %s", response.Status)
+		}
+
+		for _, release := range repoRelease {
+			// Drafts are releases whose push did not complete.
+			if release.GetDraft() {
+				continue
+			}
+			releases = append(releases, release.GetName())
+		}
+
+		if response == nil || response.NextPage == 0 {
+			break
+		}
+		opts.Page = response.NextPage
+	}
+
+	return releases, nil
+}
+
+// Location returns the repository of the client.
+func (c *realGhClient) Location() string {
+	return fmt.Sprintf("github://%s/%s", c.orgName, c.repoName)
+}
+
+// FoundRelease checks if the specified release exists in the repository. Only a 404 of the Github API means that
+// the release does not exist. Draft releases are not found, as they have no tag yet.
+func (c *realGhClient) FoundRelease(ctx context.Context, tag string) (bool, error) {
+	_, response, err := c.client.Repositories.GetReleaseByTag(ctx, c.orgName, c.repoName, tag)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list releases: %w", err)
+		if response != nil && response.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get release tag %s: %w", tag, err)
 	}
 
-	if response != nil && response.StatusCode != 200 {
-		return nil, fmt.Errorf("got unexpected status from call to remote repository: %s", response.Status)
+	return true, nil
+}
+
+// PushReleaseAssets creates a release for the given tag and uploads the release assets to it.
+// As Github releases have no artifact type, the artifact type is ignored and the metadata is
+// written to the release notes. Github releases have no digest, so an empty digest is returned.
+// The release is created as a draft and only published after all assets are uploaded. If the push fails,
+// the draft is deleted, so that no incomplete release is left behind.
+func (c *realGhClient) PushReleaseAssets(ctx context.Context, releaseAssets []assetsclient.ReleaseAsset, tag, dir, _ string, metadata map[string]string) (string, error) {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
 	}
+	sort.Strings(keys)
 
-	releases := []string{}
+	var body strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&body, "%s: %s\n", key, metadata[key])
+	}
 
-	for _, release := range repoRelease {
-		releases = append(releases, *release.Name)
+	release, _, err := c.client.Repositories.CreateRelease(ctx, c.orgName, c.repoName, &github.RepositoryRelease{
+		TagName: github.String(tag),
+		Name:    github.String(tag),
+		Body:    github.String(body.String()),
+		Draft:   github.Bool(true),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create release %s: %w", tag, err)
 	}
 
-	return releases, nil
+	for _, releaseAsset := range releaseAssets {
+		if err := c.uploadReleaseAsset(ctx, release.GetID(), dir, releaseAsset); err != nil {
+			return "", c.deleteDraftRelease(ctx, release.GetID(), tag, fmt.Errorf("failed to upload release asset %s: %w", releaseAsset.FileName, err))
+		}
+	}
+
+	if _, _, err := c.client.Repositories.EditRelease(ctx, c.orgName, c.repoName, release.GetID(), &github.RepositoryRelease{
+		Draft: github.Bool(false),
+	}); err != nil {
+		return "", c.deleteDraftRelease(ctx, release.GetID(), tag, fmt.Errorf("failed to publish release %s: %w", tag, err))
+	}
+
+	return "", nil
+}
+
+// deleteDraftRelease deletes the draft release after pushErr, so that the push can be retried. The draft is also
+// deleted if ctx was canceled.
+func (c *realGhClient) deleteDraftRelease(ctx context.Context, releaseID int64, tag string, pushErr error) error {
+	if _, err := c.client.Repositories.DeleteRelease(context.WithoutCancel(ctx), c.orgName, c.repoName, releaseID); err != nil {
+		return errors.Join(pushErr, fmt.Errorf("failed to delete incomplete draft release %s: %w", tag, err))
+	}
+
+	logging.FromContext(ctx).Info("Deleted incomplete draft release", "release", tag)
+	return pushErr
+}
+
+func (c *realGhClient) uploadReleaseAsset(ctx context.Context, releaseID int64, dir string, releaseAsset assetsclient.ReleaseAsset) error {
+	assetFile, err := os.Open(filepath.Clean(filepath.Join(dir, releaseAsset.FileName)))
+	if err != nil {
+		return fmt.Errorf("failed to open asset file: %w", err)
+	}
+	defer assetFile.Close()
+
+	mediaType := releaseAsset.MediaType
+	if mediaType == "" {
+		mediaType = "application/octet-stream"
+	}
+
+	if _, _, err := c.client.Repositories.UploadReleaseAsset(ctx, c.orgName, c.repoName, releaseID, &github.UploadOptions{
+		Name:      releaseAsset.FileName,
+		MediaType: mediaType,
+	}, assetFile); err != nil {
+		return fmt.Errorf("failed to upload asset file: %w", err)
+	}
+
+	return nil
 }
 
 func (c *realGhClient) getReleaseByTag(ctx context.Context, tag string) (*github.RepositoryRelease, *github.Response, error) {
@@ -100,6 +243,24 @@ func (c *realGhClient) getReleaseByTag(ctx context.Context, tag string) (*github
 	return repoRelease, response, nil
 }
 
+// ReleaseID returns the IDs of the release and of the downloaded release assets. Re-uploaded assets get new IDs.
+func (c *realGhClient) ReleaseID(ctx context.Context, tag string) (string, error) {
+	release, _, err := c.getReleaseByTag(ctx, tag)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch release tag %s: %w", tag, err)
+	}
+
+	assetIDs := []string{}
+	for _, asset := range release.Assets {
+		if c.shouldDownload(asset.GetName()) {
+			assetIDs = append(assetIDs, fmt.Sprintf("%s=%d", asset.GetName(), asset.GetID()))
+		}
+	}
+	sort.Strings(assetIDs)
+
+	return fmt.Sprintf("github://%s/%s/releases/%d/%s", c.orgName, c.repoName, release.GetID(), strings.Join(assetIDs, ",")), nil
+}
+
 // DownloadReleaseAssets downloads a list of release assets.
 func (c *realGhClient) DownloadReleaseAssets(ctx context.Context, tag, path string) error {
 	release, response, err := c.getReleaseByTag(ctx, tag)
@@ -116,6 +277,10 @@ func (c *realGhClient) DownloadReleaseAssets(ctx context.Context, tag, path stri
 	}
 	// Extract the release assets
 	for _, asset := range release.Assets {
+		if !c.shouldDownload(asset.GetName()) {
+			continue
+		}
+
 		assetPath := filepath.Join(path, asset.GetName())
 		// Create a temporary file (inside the dest dir) to save the downloaded asset file
 		assetFile, err := os.Create(filepath.Clean(assetPath))
@@ -150,6 +315,21 @@ func (c *realGhClient) DownloadReleaseAssets(ctx context.Context, tag, path stri
 	return nil
 }
 
+// shouldDownload returns whether the release asset with the given name has to be downloaded.
+func (c *realGhClient) shouldDownload(assetName string) bool {
+	if len(c.assetNames) == 0 {
+		return true
+	}
+
+	for _, name := range c.assetNames {
+		if name == assetName {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (c *realGhClient) handleRedirect(ctx context.Context, url string, assetFile *os.File) (reterr error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
 	if err != nil {
@@ -179,20 +359,22 @@ func (c *realGhClient) handleRedirect(ctx context.Context, url string, assetFile
 	return nil
 }
 
-func githubAndOAuthClientWithToken(ctx context.Context, creds GitConfig) (githubClient *github.Client, oauthClient *http.Client, err error) {
+func githubAndOAuthClientWithToken(ctx context.Context, creds GitConfig, opts assetsclient.Options) (githubClient *github.Client, oauthClient *http.Client, err error) {
+	httpClient := &http.Client{Transport: opts.NewTransport()}
 	if creds.GitAccessToken == "" {
-		githubClient = github.NewClient(nil)
+		githubClient = github.NewClient(httpClient)
 	} else {
 		ts := oauth2.StaticTokenSource(
 			&oauth2.Token{AccessToken: creds.GitAccessToken},
 		)
 
-		oauthClient = oauth2.NewClient(ctx, ts)
+		// oauth2 sends its requests with the HTTP client of the context.
+		oauthClient = oauth2.NewClient(context.WithValue(ctx, oauth2.HTTPClient, httpClient), ts)
 		githubClient = github.NewClient(oauthClient)
 	}
 
 	if err := verifyAccess(ctx, githubClient, creds); err != nil {
-		return nil, &http.Client{}, fmt.Errorf("failed to access Git API: %w", err)
+		return nil, &http.Client{}, logging.RedactError(fmt.Errorf("failed to access Git API: %w", err))
 	}
 
 	return githubClient, oauthClient, nil
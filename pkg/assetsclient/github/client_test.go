@@ -0,0 +1,239 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/SovereignCloudStack/csctl/pkg/assetsclient"
+	"github.com/google/go-github/v56/github"
+)
+
+// fakeGithub records the requests to the releases API of the repository org/repo.
+type fakeGithub struct {
+	mu            sync.Mutex
+	requests      []string
+	failAssetName string
+}
+
+func (f *fakeGithub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/repos/org/repo/releases":
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": 1, "draft": true}`))
+	case r.Method == http.MethodPost && r.URL.Path == "/repos/org/repo/releases/1/assets":
+		if r.URL.Query().Get("name") == f.failAssetName {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": 2}`))
+	case r.Method == http.MethodPatch && r.URL.Path == "/repos/org/repo/releases/1":
+		_, _ = w.Write([]byte(`{"id": 1, "draft": false}`))
+	case r.Method == http.MethodDelete && r.URL.Path == "/repos/org/repo/releases/1":
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && r.URL.Path == "/repos/org/repo/releases":
+		// The releases are split into two pages, the latest release is on the second one.
+		if r.URL.Query().Get("page") == "2" {
+			_, _ = w.Write([]byte(`[{"id": 3, "name": "docker-ferrol-1-27-v3"}]`))
+			return
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<http://%s/repos/org/repo/releases?page=2>; rel="next"`, r.Host))
+		_, _ = w.Write([]byte(`[{"id": 1, "name": "docker-ferrol-1-27-v1"}, {"id": 2, "name": "docker-ferrol-1-27-v2", "draft": true}]`))
+	case r.Method == http.MethodGet && r.URL.Path == "/repos/org/repo/releases/tags/v1":
+		_, _ = w.Write([]byte(`{"id": 1}`))
+	case r.Method == http.MethodGet && r.URL.Path == "/repos/org/repo/releases/tags/docker-ferrol-1-27-v1":
+		_, _ = w.Write([]byte(`{"id": 4, "assets": [
+			{"id": 10, "name": "metadata.yaml"},
+			{"id": 11, "name": "node-images.yaml"},
+			{"id": 12, "name": "sbom.json"}
+		]}`))
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/repos/org/repo/releases/assets/"):
+		// The content of an asset is its ID.
+		_, _ = w.Write([]byte("asset " + strings.TrimPrefix(r.URL.Path, "/repos/org/repo/releases/assets/")))
+	case r.Method == http.MethodGet && r.URL.Path == "/repos/org/repo/releases/tags/v2":
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message": "Not Found"}`))
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (f *fakeGithub) has(request string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, r := range f.requests {
+		if r == request {
+			return true
+		}
+	}
+	return false
+}
+
+func newTestClient(t *testing.T, handler http.Handler) *realGhClient {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := github.NewClient(server.Client())
+	client.BaseURL = serverURL
+	client.UploadURL = serverURL
+
+	return &realGhClient{client: client, httpclient: server.Client(), orgName: "org", repoName: "repo"}
+}
+
+func writeAssets(t *testing.T, names ...string) (string, []assetsclient.ReleaseAsset) {
+	t.Helper()
+
+	dir := t.TempDir()
+	assets := make([]assetsclient.ReleaseAsset, 0, len(names))
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		assets = append(assets, assetsclient.ReleaseAsset{FileName: name})
+	}
+
+	return dir, assets
+}
+
+func TestPushReleaseAssets(t *testing.T) {
+	fake := &fakeGithub{}
+	client := newTestClient(t, fake)
+	dir, assets := writeAssets(t, "metadata.yaml", "hashes.json")
+
+	if _, err := client.PushReleaseAssets(context.Background(), assets, "v1", dir, "", nil); err != nil {
+		t.Fatalf("PushReleaseAssets() failed: %v", err)
+	}
+
+	if !fake.has("PATCH /repos/org/repo/releases/1") {
+		t.Error("draft release was not published")
+	}
+	if fake.has("DELETE /repos/org/repo/releases/1") {
+		t.Error("release was deleted")
+	}
+}
+
+func TestPushReleaseAssetsPartialUpload(t *testing.T) {
+	fake := &fakeGithub{failAssetName: "hashes.json"}
+	client := newTestClient(t, fake)
+	dir, assets := writeAssets(t, "metadata.yaml", "hashes.json")
+
+	_, err := client.PushReleaseAssets(context.Background(), assets, "v1", dir, "", nil)
+	if err == nil || !strings.Contains(err.Error(), "hashes.json") {
+		t.Fatalf("PushReleaseAssets() = %v, want error for hashes.json", err)
+	}
+
+	if !fake.has("DELETE /repos/org/repo/releases/1") {
+		t.Error("incomplete draft release was not deleted")
+	}
+	if fake.has("PATCH /repos/org/repo/releases/1") {
+		t.Error("incomplete draft release was published")
+	}
+}
+
+func TestFoundRelease(t *testing.T) {
+	client := newTestClient(t, &fakeGithub{})
+
+	tests := []struct {
+		tag       string
+		wantFound bool
+		wantErr   bool
+	}{
+		{tag: "v1", wantFound: true},
+		{tag: "v2", wantFound: false},
+		// The fake server answers with 500 for unknown tags.
+		{tag: "v3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			found, err := client.FoundRelease(context.Background(), tt.tag)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FoundRelease() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if found != tt.wantFound {
+				t.Errorf("FoundRelease() = %v, want %v", found, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestListRelease(t *testing.T) {
+	fake := &fakeGithub{}
+	client := newTestClient(t, fake)
+
+	releases, err := client.ListRelease(context.Background())
+	if err != nil {
+		t.Fatalf("ListRelease() failed: %v", err)
+	}
+
+	// The draft is skipped, as its push did not complete.
+	want := []string{"docker-ferrol-1-27-v1", "docker-ferrol-1-27-v3"}
+	if !slices.Equal(releases, want) {
+		t.Errorf("ListRelease() = %v, want %v", releases, want)
+	}
+}
+
+func TestDownloadReleaseAssets(t *testing.T) {
+	fake := &fakeGithub{}
+	client := newTestClient(t, fake)
+	client.assetNames = NewFactoryForAssets(assetsclient.Options{}, "metadata.yaml", "node-images.yaml").(*factory).assetNames
+
+	dir := t.TempDir()
+	if err := client.DownloadReleaseAssets(context.Background(), "docker-ferrol-1-27-v1", dir); err != nil {
+		t.Fatalf("DownloadReleaseAssets() failed: %v", err)
+	}
+
+	for name, want := range map[string]string{"metadata.yaml": "asset 10", "node-images.yaml": "asset 11"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("%s was not downloaded: %v", name, err)
+		}
+		if string(data) != want {
+			t.Errorf("content of %s = %q, want %q", name, data, want)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "sbom.json")); !os.IsNotExist(err) {
+		t.Errorf("sbom.json was downloaded, although it was not requested: %v", err)
+	}
+	if fake.has("GET /repos/org/repo/releases/assets/12") {
+		t.Error("sbom.json was fetched, although it was not requested")
+	}
+}
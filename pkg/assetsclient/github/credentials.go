@@ -19,6 +19,8 @@ package github
 import (
 	"fmt"
 	"os"
+
+	"github.com/SovereignCloudStack/csctl/pkg/logging"
 )
 
 const (
@@ -69,6 +71,7 @@ func NewGitConfig() (GitConfig, error) {
 	gitCfg.GitRepoName = val
 
 	gitCfg.GitAccessToken = os.Getenv(EnvGitAccessToken)
+	logging.AddSecret(gitCfg.GitAccessToken)
 
 	return gitCfg, nil
 }
@@ -0,0 +1,356 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gitlab provides utilities to work with the generic package registry of GitLab.
+// Each release is a version of a generic package, the release assets are the files of the version.
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/SovereignCloudStack/csctl/pkg/assetsclient"
+)
+
+// maxErrorBodySize is the maximum number of bytes of an error response that is included in the error.
+const maxErrorBodySize = 1024
+
+var errReleaseNotFound = errors.New("release not found")
+
+// Client is a client for the generic package registry of a GitLab project.
+type Client struct {
+	httpClient *http.Client
+	config     Config
+	assetNames []string
+}
+
+type factory struct {
+	options    assetsclient.Options
+	assetNames []string
+}
+
+// gitlabPackage is a package as returned by the packages API of GitLab.
+type gitlabPackage struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// gitlabPackageFile is a package file as returned by the packages API of GitLab.
+type gitlabPackageFile struct {
+	FileName string `json:"file_name"`
+}
+
+var _ = assetsclient.Client(&Client{})
+
+var _ = assetsclient.Pusher(&Client{})
+
+var _ = assetsclient.Factory(&factory{})
+
+var _ = assetsclient.ReleaseIdentifier(&Client{})
+
+// NewFactory returns a new factory for GitLab clients.
+func NewFactory(opts assetsclient.Options) assetsclient.Factory {
+	return &factory{options: opts}
+}
+
+// NewFactoryForAssets returns a new factory for GitLab clients that only download the release assets
+// with the given names. If no names are given, all release assets are downloaded.
+func NewFactoryForAssets(opts assetsclient.Options, assetNames ...string) assetsclient.Factory {
+	return &factory{options: opts, assetNames: assetNames}
+}
+
+func (f *factory) NewClient(_ context.Context) (assetsclient.Client, error) {
+	client, err := NewClient(f.options)
+	if err != nil {
+		return nil, err
+	}
+
+	client.assetNames = f.assetNames
+	return client, nil
+}
+
+// NewClient creates a new GitLab client from the environment.
+func NewClient(opts assetsclient.Options) (*Client, error) {
+	config, err := NewConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitLab config: %w", err)
+	}
+
+	return &Client{
+		httpClient: &http.Client{Transport: opts.NewTransport()},
+		config:     config,
+	}, nil
+}
+
+// ListRelease returns the versions of the generic package. All pages of the GitLab API are fetched.
+func (c *Client) ListRelease(ctx context.Context) ([]string, error) {
+	packages, err := c.listPackages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	releases := make([]string, 0, len(packages))
+	for _, p := range packages {
+		releases = append(releases, p.Version)
+	}
+
+	return releases, nil
+}
+
+// FoundRelease checks if the specified release exists in the package registry.
+func (c *Client) FoundRelease(ctx context.Context, tag string) (bool, error) {
+	if _, err := c.getPackage(ctx, tag); err != nil {
+		if errors.Is(err, errReleaseNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ReleaseID returns the ID of the package version of the release and the names of the downloaded files.
+func (c *Client) ReleaseID(ctx context.Context, tag string) (string, error) {
+	p, err := c.getPackage(ctx, tag)
+	if err != nil {
+		return "", fmt.Errorf("failed to get release %s: %w", tag, err)
+	}
+
+	return fmt.Sprintf("gitlab://%s/%s/packages/%d/%s", c.config.URL, c.config.Project, p.ID, strings.Join(c.assetNames, ",")), nil
+}
+
+// DownloadReleaseAssets downloads the files of the release to path.
+func (c *Client) DownloadReleaseAssets(ctx context.Context, tag, path string) error {
+	p, err := c.getPackage(ctx, tag)
+	if err != nil {
+		return fmt.Errorf("failed to get release %s: %w", tag, err)
+	}
+
+	var files []gitlabPackageFile
+	if err := c.getAllPages(ctx, fmt.Sprintf("%s/packages/%d/package_files", c.projectURL(), p.ID), func(data []byte) error {
+		var page []gitlabPackageFile
+		if err := json.Unmarshal(data, &page); err != nil {
+			return fmt.Errorf("failed to unmarshal package files: %w", err)
+		}
+		files = append(files, page...)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to list files of release %s: %w", tag, err)
+	}
+
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	for _, file := range files {
+		if len(c.assetNames) > 0 && !slices.Contains(c.assetNames, file.FileName) {
+			continue
+		}
+
+		if err := c.downloadFile(ctx, tag, file.FileName, filepath.Join(path, file.FileName)); err != nil {
+			return fmt.Errorf("failed to download release asset %s: %w", file.FileName, err)
+		}
+	}
+
+	return nil
+}
+
+// PushReleaseAssets uploads the release assets as files of a new version of the generic package.
+// Generic packages have neither an artifact type nor metadata, so both are ignored. An empty digest is returned.
+func (c *Client) PushReleaseAssets(ctx context.Context, releaseAssets []assetsclient.ReleaseAsset, tag, dir, _ string, _ map[string]string) (string, error) {
+	for _, releaseAsset := range releaseAssets {
+		if err := c.uploadFile(ctx, tag, dir, releaseAsset); err != nil {
+			return "", fmt.Errorf("failed to upload release asset %s: %w", releaseAsset.FileName, err)
+		}
+	}
+
+	return "", nil
+}
+
+// Location returns the package of the GitLab project the client publishes to.
+func (c *Client) Location() string {
+	return fmt.Sprintf("gitlab://%s/%s/packages/generic/%s", c.config.URL, c.config.Project, c.config.PackageName)
+}
+
+func (c *Client) projectURL() string {
+	return fmt.Sprintf("%s/api/v4/projects/%s", c.config.URL, url.PathEscape(c.config.Project))
+}
+
+func (c *Client) fileURL(tag, fileName string) string {
+	return fmt.Sprintf("%s/packages/generic/%s/%s/%s", c.projectURL(),
+		url.PathEscape(c.config.PackageName), url.PathEscape(tag), url.PathEscape(fileName))
+}
+
+// listPackages returns all versions of the generic package.
+func (c *Client) listPackages(ctx context.Context) ([]gitlabPackage, error) {
+	query := url.Values{}
+	query.Set("package_type", "generic")
+	query.Set("package_name", c.config.PackageName)
+
+	var packages []gitlabPackage
+	if err := c.getAllPages(ctx, fmt.Sprintf("%s/packages?%s", c.projectURL(), query.Encode()), func(data []byte) error {
+		var page []gitlabPackage
+		if err := json.Unmarshal(data, &page); err != nil {
+			return fmt.Errorf("failed to unmarshal packages: %w", err)
+		}
+
+		// The package name filter of GitLab matches substrings.
+		for _, p := range page {
+			if p.Name == c.config.PackageName {
+				packages = append(packages, p)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list releases: %w", err)
+	}
+
+	return packages, nil
+}
+
+func (c *Client) getPackage(ctx context.Context, tag string) (gitlabPackage, error) {
+	packages, err := c.listPackages(ctx)
+	if err != nil {
+		return gitlabPackage{}, err
+	}
+
+	for _, p := range packages {
+		if p.Version == tag {
+			return p, nil
+		}
+	}
+
+	return gitlabPackage{}, fmt.Errorf("%w: %s", errReleaseNotFound, tag)
+}
+
+// getAllPages calls handlePage with the body of each page of the paginated GitLab API.
+func (c *Client) getAllPages(ctx context.Context, pageURL string, handlePage func([]byte) error) error {
+	page := "1"
+	for page != "" {
+		separator := "?"
+		if u, err := url.Parse(pageURL); err == nil && u.RawQuery != "" {
+			separator = "&"
+		}
+
+		resp, err := c.do(ctx, http.MethodGet, fmt.Sprintf("%s%sper_page=100&page=%s", pageURL, separator, page), http.NoBody)
+		if err != nil {
+			return err
+		}
+
+		data, err := io.ReadAll(resp.Body)
+		if closeErr := resp.Body.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+
+		if err := handlePage(data); err != nil {
+			return err
+		}
+
+		page = resp.Header.Get("X-Next-Page")
+	}
+
+	return nil
+}
+
+func (c *Client) downloadFile(ctx context.Context, tag, fileName, dst string) (reterr error) {
+	resp, err := c.do(ctx, http.MethodGet, c.fileURL(tag, fileName), http.NoBody)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	file, err := os.Create(filepath.Clean(dst))
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", dst, err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil && reterr == nil {
+			reterr = fmt.Errorf("failed to close file %s: %w", dst, err)
+		}
+	}()
+
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		return fmt.Errorf("failed to save file %s from HTTP response: %w", dst, err)
+	}
+
+	return nil
+}
+
+func (c *Client) uploadFile(ctx context.Context, tag, dir string, releaseAsset assetsclient.ReleaseAsset) error {
+	file, err := os.Open(filepath.Clean(filepath.Join(dir, releaseAsset.FileName)))
+	if err != nil {
+		return fmt.Errorf("failed to open asset file: %w", err)
+	}
+	defer file.Close()
+
+	resp, err := c.do(ctx, http.MethodPut, c.fileURL(tag, releaseAsset.FileName), file)
+	if err != nil {
+		return err
+	}
+
+	if err := resp.Body.Close(); err != nil {
+		return fmt.Errorf("failed to close response: %w", err)
+	}
+
+	return nil
+}
+
+// do sends the request with the token of the config. Responses with a status other than 2xx are returned as error.
+func (c *Client) do(ctx context.Context, method, requestURL string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// GitLab needs the size of uploaded files.
+	if file, ok := body.(*os.File); ok {
+		info, err := file.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", file.Name(), err)
+		}
+		req.ContentLength = info.Size()
+	}
+
+	if c.config.token != "" {
+		req.Header.Set(c.config.tokenHeader, c.config.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s %s: %w", method, req.URL.Redacted(), err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return nil, fmt.Errorf("failed to %s %s: got status %s: %s", method, req.URL.Redacted(), resp.Status, message)
+	}
+
+	return resp, nil
+}
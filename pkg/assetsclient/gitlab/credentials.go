@@ -0,0 +1,91 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitlab
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/SovereignCloudStack/csctl/pkg/logging"
+)
+
+const (
+	// EnvGitlabURL is the URL of the GitLab instance. It defaults to https://gitlab.com.
+	EnvGitlabURL = "GITLAB_URL"
+
+	// EnvGitlabProject is the ID or the path of the project, e.g. my-group/cluster-stacks.
+	EnvGitlabProject = "GITLAB_PROJECT"
+
+	// EnvGitlabToken is a personal, project or group access token.
+	EnvGitlabToken = "GITLAB_TOKEN"
+
+	// EnvGitlabPackageName is the name of the generic package. It defaults to cluster-stacks.
+	EnvGitlabPackageName = "GITLAB_PACKAGE_NAME"
+
+	// envCIJobToken is the job token of GitLab CI. It is used if GITLAB_TOKEN is not set.
+	envCIJobToken = "CI_JOB_TOKEN"
+
+	defaultGitlabURL   = "https://gitlab.com"
+	defaultPackageName = "cluster-stacks"
+)
+
+// Config contains necessary data to connect to GitLab.
+type Config struct {
+	URL         string
+	Project     string
+	PackageName string
+	token       string
+	tokenHeader string
+}
+
+// NewConfig reads the configuration of the GitLab client from the environment.
+func NewConfig() (Config, error) {
+	config := Config{
+		URL:         defaultGitlabURL,
+		PackageName: defaultPackageName,
+	}
+
+	if val := os.Getenv(EnvGitlabURL); val != "" {
+		if _, err := url.ParseRequestURI(val); err != nil {
+			return Config{}, fmt.Errorf("environment variable %s has invalid value %q: %w", EnvGitlabURL, val, err)
+		}
+		config.URL = strings.TrimSuffix(val, "/")
+	}
+
+	config.Project = os.Getenv(EnvGitlabProject)
+	if config.Project == "" {
+		return Config{}, fmt.Errorf("environment variable %s is not set", EnvGitlabProject)
+	}
+
+	if val := os.Getenv(EnvGitlabPackageName); val != "" {
+		config.PackageName = val
+	}
+
+	// Public projects can be read without token.
+	if val := os.Getenv(EnvGitlabToken); val != "" {
+		config.token = val
+		config.tokenHeader = "PRIVATE-TOKEN"
+	} else if val := os.Getenv(envCIJobToken); val != "" {
+		config.token = val
+		config.tokenHeader = "JOB-TOKEN"
+	}
+	logging.AddSecret(config.token)
+
+	return config, nil
+}
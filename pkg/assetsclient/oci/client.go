@@ -19,13 +19,17 @@ package oci
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 
 	"github.com/SovereignCloudStack/csctl/pkg/assetsclient"
+	"github.com/SovereignCloudStack/csctl/pkg/logging"
 	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
 	"oras.land/oras-go/v2/content/file"
+	"oras.land/oras-go/v2/errdef"
 	"oras.land/oras-go/v2/registry"
 	"oras.land/oras-go/v2/registry/remote"
 	"oras.land/oras-go/v2/registry/remote/auth"
@@ -34,89 +38,100 @@ import (
 // Client represents the client for oci repository.
 type Client struct {
 	Repository *remote.Repository
+	// concurrency is the number of blobs that are pushed or pulled at the same time. Zero means DefaultConcurrency.
+	concurrency int
+	progress    ProgressFunc
 }
 
-type factory struct{}
+// Options configures OCI clients.
+type Options struct {
+	assetsclient.Options
+	// TLS configures the TLS connection to the registry.
+	TLS TLSOptions
+	// Concurrency is the number of blobs that are pushed or pulled at the same time. Zero means DefaultConcurrency.
+	Concurrency int
+	// Progress is called while pushing and pulling release assets. No progress is reported if it is nil.
+	Progress ProgressFunc
+}
+
+type factory struct {
+	options Options
+}
 
 // NewFactory returns a new factory for OCI clients.
-func NewFactory() assetsclient.Factory {
-	return &factory{}
+func NewFactory(opts Options) assetsclient.Factory {
+	return &factory{options: opts}
 }
 
 var _ = assetsclient.Factory(&factory{})
 
 var _ = assetsclient.Client(&Client{})
 
+var _ = assetsclient.Pusher(&Client{})
+
+var _ = assetsclient.Deleter(&Client{})
+
+var _ = assetsclient.Tagger(&Client{})
+
+var _ = assetsclient.ReleaseIdentifier(&Client{})
+
 // NewClient creates a new ociClient.
-func NewClient() (*Client, error) {
+func NewClient(opts Options) (*Client, error) {
 	config, err := newOCIConfig()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create OCI config: %w", err)
 	}
 
-	client := auth.Client{
-		Credential: auth.StaticCredential(config.registry, auth.Credential{
-			AccessToken: config.accessToken,
-			Username:    config.username,
-			Password:    config.password,
-		}),
-	}
-
-	repository, err := remote.NewRepository(config.repository)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create OCI client to remote repository %s: %w", config.repository, err)
-	}
-
-	repository.Client = &client
-	return &Client{Repository: repository}, nil
+	return newClient(config.repository, config, opts)
 }
 
 // NewClientForRepository creates a new ociClient for the provided repository.
-func NewClientForRepository(repo string) (*Client, error) {
+func NewClientForRepository(repo string, opts Options) (*Client, error) {
 	config, err := newOCIConfigWithoutRepository()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create OCI config: %w", err)
 	}
 
-	client := auth.Client{
-		Credential: auth.StaticCredential(config.registry, auth.Credential{
-			AccessToken: config.accessToken,
-			Username:    config.username,
-			Password:    config.password,
-		}),
+	return newClient(repo, config, opts)
+}
+
+func (f *factory) NewClient(_ context.Context) (assetsclient.Client, error) {
+	config, err := newOCIConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OCI config: %w", err)
 	}
 
-	repository, err := remote.NewRepository(repo)
+	client, err := newClient(config.repository, config, f.options)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create OCI client to remote repository %s: %w", config.repository, err)
+		return nil, err
 	}
 
-	repository.Client = &client
-	return &Client{Repository: repository}, nil
+	return client, nil
 }
 
-func (*factory) NewClient(ctx context.Context) (assetsclient.Client, error) {
-	_ = ctx
-	config, err := newOCIConfig()
+func newClient(repo string, config ociConfig, opts Options) (*Client, error) {
+	httpClient, err := opts.TLS.newHTTPClient(opts.NewTransport())
 	if err != nil {
-		return nil, fmt.Errorf("failed to create OCI config: %w", err)
+		return nil, fmt.Errorf("failed to configure TLS: %w", err)
 	}
 
-	client := auth.Client{
-		Credential: auth.StaticCredential(config.registry, auth.Credential{
-			AccessToken: config.accessToken,
-			Username:    config.username,
-			Password:    config.password,
-		}),
+	repository, err := remote.NewRepository(repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OCI client to remote repository %s: %w", repo, err)
 	}
 
-	repository, err := remote.NewRepository(config.repository)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create OCI client to remote repository %s: %w", config.repository, err)
+	if config.registry == "" {
+		config.registry = repository.Reference.Registry
+	}
+
+	client := auth.Client{
+		Client:     httpClient,
+		Credential: config.credential(),
 	}
 
 	repository.Client = &client
-	return &Client{Repository: repository}, nil
+	repository.PlainHTTP = config.plainHTTP
+	return &Client{Repository: repository, concurrency: opts.Concurrency, progress: opts.Progress}, nil
 }
 
 // ListRelease returns a list of releases in the repository.
@@ -130,12 +145,56 @@ func (c *Client) ListRelease(ctx context.Context) ([]string, error) {
 }
 
 // FoundRelease checks if the specified release exists in the repository.
-func (c *Client) FoundRelease(ctx context.Context, tag string) bool {
+func (c *Client) FoundRelease(ctx context.Context, tag string) (bool, error) {
 	if _, err := c.Repository.Resolve(ctx, tag); err != nil {
-		return false
+		if errors.Is(err, errdef.ErrNotFound) {
+			return false, nil
+		}
+		return false, logging.RedactError(fmt.Errorf("failed to resolve release tag %q: %w", tag, err))
+	}
+
+	return true, nil
+}
+
+// ReleaseID returns the repository and the digest of the manifest of the specified release.
+func (c *Client) ReleaseID(ctx context.Context, tag string) (string, error) {
+	desc, err := c.Repository.Resolve(ctx, tag)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve release tag %q: %w", tag, err)
+	}
+
+	return fmt.Sprintf("oci://%s@%s", c.Repository.Reference, desc.Digest), nil
+}
+
+// DeleteRelease deletes the manifest of the specified release from the repository.
+// The blobs referenced by the manifest might be shared with other releases. They are
+// left to the garbage collection of the registry.
+func (c *Client) DeleteRelease(ctx context.Context, tag string) error {
+	desc, err := c.Repository.Resolve(ctx, tag)
+	if err != nil {
+		return fmt.Errorf("failed to resolve release tag %q: %w", tag, err)
+	}
+
+	if err := c.Repository.Delete(ctx, desc); err != nil {
+		return fmt.Errorf("failed to delete manifest %s of release tag %q: %w", desc.Digest, tag, err)
+	}
+
+	return nil
+}
+
+// TagRelease tags the manifest of the specified release additionally with alias.
+// An existing alias is overwritten by the registry in a single manifest update.
+func (c *Client) TagRelease(ctx context.Context, tag, alias string) error {
+	desc, err := c.Repository.Resolve(ctx, tag)
+	if err != nil {
+		return fmt.Errorf("failed to resolve release tag %q: %w", tag, err)
+	}
+
+	if err := c.Repository.Tag(ctx, desc, alias); err != nil {
+		return fmt.Errorf("failed to tag release %q with %q: %w", tag, alias, err)
 	}
 
-	return true
+	return nil
 }
 
 // CopyRelease copies the release artifact to target repository.
@@ -146,8 +205,9 @@ func (c *Client) CopyRelease(ctx context.Context, sourceTag, targetRepository, t
 	}
 
 	destinationRepository.Client = c.Repository.Client
+	destinationRepository.PlainHTTP = c.Repository.PlainHTTP
 
-	if _, err := oras.Copy(ctx, c.Repository, sourceTag, destinationRepository, targetTag, oras.DefaultCopyOptions); err != nil {
+	if _, err := oras.Copy(ctx, c.Repository, sourceTag, destinationRepository, targetTag, c.copyOptions()); err != nil {
 		return fmt.Errorf("failed to copy release from source repository %q to destination repository %q: %w", c.Repository.Reference, targetRepository, err)
 	}
 
@@ -168,28 +228,88 @@ func (c *Client) DownloadReleaseAssets(ctx context.Context, tag, path string) (r
 		}
 	}()
 
-	_, err = oras.Copy(ctx, c.Repository, tag, dest, tag, oras.DefaultCopyOptions)
+	root, err := oras.Copy(ctx, c.withProgress(c.Repository), tag, dest, tag, c.copyOptions())
 	if err != nil {
-		return fmt.Errorf("failed to copy repository artifacts to path %s: %w", path, err)
+		return logging.RedactError(fmt.Errorf("failed to copy repository artifacts to path %s: %w", path, err))
+	}
+
+	// The artifact type is informational. Failing to read it does not fail the download.
+	data, err := content.FetchAll(ctx, dest, root)
+	if err == nil {
+		manifest := &imagev1.Manifest{}
+		if err := json.Unmarshal(data, manifest); err == nil {
+			logging.FromContext(ctx).Info("Downloaded release", "release", tag, "artifactType", manifest.ArtifactType)
+		}
 	}
 
 	return nil
 }
 
+// Location returns the repository of the client.
+func (c *Client) Location() string {
+	return "oci://" + c.Repository.Reference.String()
+}
+
+// ReleaseDigest returns the digest of the manifest PushReleaseAssets would push for the provided release assets.
+// The manifest contains the creation time, unless it is set in the annotations. Set it to get the same digest when pushing.
+func (c *Client) ReleaseDigest(ctx context.Context, releaseAssets []assetsclient.ReleaseAsset, dir, artifactType string, annotations map[string]string) (string, error) {
+	filestore, err := file.New(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to create new file store: %w", err)
+	}
+
+	defer filestore.Close()
+
+	manifestDesc, err := packReleaseAssets(ctx, filestore, releaseAssets, artifactType, annotations)
+	if err != nil {
+		return "", err
+	}
+
+	return manifestDesc.Digest.String(), nil
+}
+
 // PushReleaseAssets pushes the provided release assets as an artifact into the repository.
-func (c *Client) PushReleaseAssets(ctx context.Context, releaseAssets []assetsclient.ReleaseAsset, tag, dir, artifactType string, annotations map[string]string) error {
+// It verifies that the tag resolves to the pushed manifest in the repository and returns its digest.
+func (c *Client) PushReleaseAssets(ctx context.Context, releaseAssets []assetsclient.ReleaseAsset, tag, dir, artifactType string, annotations map[string]string) (string, error) {
 	filestore, err := file.New(dir)
 	if err != nil {
-		return fmt.Errorf("failed to create new file store: %w", err)
+		return "", fmt.Errorf("failed to create new file store: %w", err)
 	}
 
 	defer filestore.Close()
 
+	manifestDesc, err := packReleaseAssets(ctx, filestore, releaseAssets, artifactType, annotations)
+	if err != nil {
+		return "", err
+	}
+
+	if err := filestore.Tag(ctx, manifestDesc, tag); err != nil {
+		return "", fmt.Errorf("failed to tag the manifest descriptor: %w", err)
+	}
+
+	if _, err := oras.Copy(ctx, c.withProgress(filestore), tag, c.Repository, tag, c.copyOptions()); err != nil {
+		return "", logging.RedactError(fmt.Errorf("failed to copy release assets to remote repository: %w", err))
+	}
+
+	remoteDesc, err := c.Repository.Resolve(ctx, tag)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve pushed release %s: %w", tag, err)
+	}
+
+	if remoteDesc.Digest != manifestDesc.Digest {
+		return "", fmt.Errorf("digest of pushed release %s in remote repository is %s, expected %s", tag, remoteDesc.Digest, manifestDesc.Digest)
+	}
+
+	return manifestDesc.Digest.String(), nil
+}
+
+// packReleaseAssets adds the release assets to the file store and packs the manifest of the release.
+func packReleaseAssets(ctx context.Context, filestore *file.Store, releaseAssets []assetsclient.ReleaseAsset, artifactType string, annotations map[string]string) (imagev1.Descriptor, error) {
 	descriptors := []imagev1.Descriptor{}
 	for _, releaseAsset := range releaseAssets {
 		fileDescriptor, err := filestore.Add(ctx, releaseAsset.FileName, releaseAsset.MediaType, "")
 		if err != nil {
-			return fmt.Errorf("failed to add file asset %s to filestore: %w", releaseAsset.FileName, err)
+			return imagev1.Descriptor{}, fmt.Errorf("failed to add file asset %s to filestore: %w", releaseAsset.FileName, err)
 		}
 
 		descriptors = append(descriptors, fileDescriptor)
@@ -200,16 +320,8 @@ func (c *Client) PushReleaseAssets(ctx context.Context, releaseAssets []assetscl
 		ManifestAnnotations: annotations,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to generate manifest descriptor: %w", err)
+		return imagev1.Descriptor{}, fmt.Errorf("failed to generate manifest descriptor: %w", err)
 	}
 
-	if err := filestore.Tag(ctx, manifestDesc, tag); err != nil {
-		return fmt.Errorf("failed to tag the manifest descriptor: %w", err)
-	}
-
-	if _, err := oras.Copy(ctx, filestore, tag, c.Repository, tag, oras.DefaultCopyOptions); err != nil {
-		return fmt.Errorf("failed to copy release assets to remote repository: %w", err)
-	}
-
-	return nil
+	return manifestDesc, nil
 }
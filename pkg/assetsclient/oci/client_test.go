@@ -0,0 +1,264 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/SovereignCloudStack/csctl/pkg/assetsclient"
+	"github.com/SovereignCloudStack/csctl/pkg/mediatype"
+	"github.com/opencontainers/go-digest"
+	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// memoryRegistry keeps the blobs and manifests of the repository "stacks" in memory. It supports what pushing and
+// pulling a release needs.
+type memoryRegistry struct {
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string]memoryManifest
+	uploads   int
+	// authorized records whether any request had an Authorization header.
+	authorized bool
+}
+
+type memoryManifest struct {
+	mediaType string
+	data      []byte
+}
+
+func newMemoryRegistry() *memoryRegistry {
+	return &memoryRegistry{blobs: map[string][]byte{}, manifests: map[string]memoryManifest{}}
+}
+
+func (m *memoryRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "" {
+		m.authorized = true
+	}
+
+	path, ok := strings.CutPrefix(r.URL.Path, "/v2/stacks/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case path == "blobs/uploads/" && r.Method == http.MethodPost:
+		m.uploads++
+		w.Header().Set("Location", fmt.Sprintf("/v2/stacks/blobs/uploads/%d", m.uploads))
+		w.WriteHeader(http.StatusAccepted)
+	case strings.HasPrefix(path, "blobs/uploads/") && r.Method == http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		dgst := r.URL.Query().Get("digest")
+		if digest.FromBytes(data).String() != dgst {
+			http.Error(w, "digest mismatch", http.StatusBadRequest)
+			return
+		}
+		m.blobs[dgst] = data
+		w.Header().Set("Docker-Content-Digest", dgst)
+		w.WriteHeader(http.StatusCreated)
+	case strings.HasPrefix(path, "blobs/"):
+		data, ok := m.blobs[strings.TrimPrefix(path, "blobs/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		m.write(w, r, "application/octet-stream", data)
+	case strings.HasPrefix(path, "manifests/") && r.Method == http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		manifest := memoryManifest{mediaType: r.Header.Get("Content-Type"), data: data}
+		dgst := digest.FromBytes(data).String()
+		m.manifests[dgst] = manifest
+		m.manifests[strings.TrimPrefix(path, "manifests/")] = manifest
+		w.Header().Set("Docker-Content-Digest", dgst)
+		w.WriteHeader(http.StatusCreated)
+	case strings.HasPrefix(path, "manifests/"):
+		manifest, ok := m.manifests[strings.TrimPrefix(path, "manifests/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		m.write(w, r, manifest.mediaType, manifest.data)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (m *memoryRegistry) write(w http.ResponseWriter, r *http.Request, mediaType string, data []byte) {
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("Docker-Content-Digest", digest.FromBytes(data).String())
+	w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+	if r.Method == http.MethodGet {
+		_, _ = w.Write(data)
+	}
+}
+
+func TestPushReleaseAssetsCustomMediaTypes(t *testing.T) {
+	mediaTypesFile := filepath.Join(t.TempDir(), "media-types.yaml")
+	if err := os.WriteFile(mediaTypesFile, []byte("metadata: application/yaml\nclusterClass: application/x-tar\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mapping, err := mediatype.ReadMapping(mediaTypesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"metadata.yaml": "apiVersion: metadata.clusterstack.x-k8s.io/v1alpha1\n",
+		"docker-ferrol-1-27-cluster-class-v1.tgz": "cluster class",
+		"hashes.json": "{}",
+	}
+
+	src := t.TempDir()
+	releaseAssets := []assetsclient.ReleaseAsset{}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		mediaType, err := mapping.ForFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		releaseAssets = append(releaseAssets, assetsclient.ReleaseAsset{FileName: name, MediaType: mediaType})
+	}
+
+	client := newTestClient(t, newMemoryRegistry())
+	ctx := context.Background()
+
+	if _, err := client.PushReleaseAssets(ctx, releaseAssets, "docker-ferrol-1-27-v1", src, mediatype.ArtifactType, nil); err != nil {
+		t.Fatalf("PushReleaseAssets() failed: %v", err)
+	}
+
+	manifest, err := client.fetchManifest(ctx, "docker-ferrol-1-27-v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantMediaTypes := map[string]string{
+		"metadata.yaml": "application/yaml",
+		"docker-ferrol-1-27-cluster-class-v1.tgz": "application/x-tar",
+		"hashes.json": mediatype.Hashes,
+	}
+	for _, layer := range manifest.Layers {
+		name := layer.Annotations[imagev1.AnnotationTitle]
+		if layer.MediaType != wantMediaTypes[name] {
+			t.Errorf("media type of %s is %s, want %s", name, layer.MediaType, wantMediaTypes[name])
+		}
+	}
+
+	dst := t.TempDir()
+	if err := client.DownloadReleaseAssets(ctx, "docker-ferrol-1-27-v1", dst); err != nil {
+		t.Fatalf("DownloadReleaseAssets() failed: %v", err)
+	}
+
+	for name, content := range files {
+		data, err := os.ReadFile(filepath.Join(dst, name))
+		if err != nil {
+			t.Fatalf("failed to read downloaded file: %v", err)
+		}
+		if string(data) != content {
+			t.Errorf("downloaded %s = %q, want %q", name, data, content)
+		}
+	}
+}
+
+func TestNewClientAnonymous(t *testing.T) {
+	registry := newMemoryRegistry()
+
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "metadata.yaml"), []byte("metadata"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	releaseAssets := []assetsclient.ReleaseAsset{{FileName: "metadata.yaml", MediaType: mediatype.Metadata}}
+
+	ctx := context.Background()
+	if _, err := newTestClient(t, registry).PushReleaseAssets(ctx, releaseAssets, "docker-ferrol-1-27-v1", src, mediatype.ArtifactType, nil); err != nil {
+		t.Fatalf("PushReleaseAssets() failed: %v", err)
+	}
+
+	server := httptest.NewServer(registry)
+	t.Cleanup(server.Close)
+
+	t.Setenv(envOCIRepository, strings.TrimPrefix(server.URL, "http://")+"/stacks")
+	t.Setenv(envOCIPlainHTTP, "true")
+	t.Setenv(envOCIRegistry, "")
+	t.Setenv(envOCIAccessToken, "")
+	t.Setenv(envOCIUsername, "")
+	t.Setenv(envOCIPassword, "")
+
+	// Without credentials in the environment, the client only asks the docker config for credentials if the registry
+	// challenges it. The public registry never does.
+	client, err := NewClient(Options{})
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+
+	found, err := client.FoundRelease(ctx, "docker-ferrol-1-27-v1")
+	if err != nil || !found {
+		t.Fatalf("FoundRelease() = %v, %v, want true", found, err)
+	}
+
+	if err := client.DownloadReleaseAssets(ctx, "docker-ferrol-1-27-v1", t.TempDir()); err != nil {
+		t.Fatalf("DownloadReleaseAssets() failed: %v", err)
+	}
+
+	if registry.authorized {
+		t.Error("expected anonymous requests, got an Authorization header")
+	}
+}
+
+func TestNewClientProxy(t *testing.T) {
+	// The registry only exists behind the proxy.
+	proxy := httptest.NewServer(&fakeRegistry{})
+	t.Cleanup(proxy.Close)
+
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := newClient("registry.example.com/stacks", ociConfig{plainHTTP: true}, Options{Options: assetsclient.Options{Proxy: proxyURL}})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	found, err := client.FoundRelease(context.Background(), "docker-ferrol-1-27-v1")
+	if err != nil || !found {
+		t.Fatalf("FoundRelease() = %v, %v, want true", found, err)
+	}
+}
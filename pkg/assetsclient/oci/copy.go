@@ -0,0 +1,35 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"oras.land/oras-go/v2"
+)
+
+// DefaultConcurrency is the default number of blobs that are copied at the same time, like oras does.
+const DefaultConcurrency = 3
+
+// copyOptions returns the options for copying releases.
+func (c *Client) copyOptions() oras.CopyOptions {
+	options := oras.DefaultCopyOptions
+	options.Concurrency = DefaultConcurrency
+	if c.concurrency > 0 {
+		options.Concurrency = c.concurrency
+	}
+
+	return options
+}
@@ -17,9 +17,17 @@ limitations under the License.
 package oci
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+
+	"github.com/SovereignCloudStack/csctl/pkg/logging"
+	dockerconfig "github.com/docker/cli/cli/config"
+	"oras.land/oras-go/v2/registry"
+	"oras.land/oras-go/v2/registry/remote/auth"
 )
 
 const (
@@ -28,6 +36,10 @@ const (
 	envOCIAccessToken = "OCI_ACCESS_TOKEN"
 	envOCIUsername    = "OCI_USERNAME"
 	envOCIPassword    = "OCI_PASSWORD"
+
+	// envOCIPlainHTTP makes the client talk plain HTTP to the registry. This should only be used for testing,
+	// e.g. with a local registry on localhost:5000.
+	envOCIPlainHTTP = "CSCTL_OCI_PLAIN_HTTP"
 )
 
 type ociConfig struct {
@@ -36,58 +48,60 @@ type ociConfig struct {
 	accessToken string
 	username    string
 	password    string
+	plainHTTP   bool
 }
 
 func newOCIConfig() (ociConfig, error) {
-	var config ociConfig
-
-	val := os.Getenv(envOCIRegistry)
-	if val == "" {
-		return ociConfig{}, fmt.Errorf("environment variable %s is not set", envOCIRegistry)
+	config, err := newOCIConfigWithoutRepository()
+	if err != nil {
+		return ociConfig{}, err
 	}
-	config.registry = val
 
-	val = os.Getenv(envOCIRepository)
+	val := os.Getenv(envOCIRepository)
 	if val == "" {
 		return ociConfig{}, fmt.Errorf("environment variable %s is not set", envOCIRepository)
 	}
+	if err := validateRepository(val); err != nil {
+		return ociConfig{}, err
+	}
 	config.repository = val
 
-	val = os.Getenv(envOCIAccessToken)
-	if val != "" {
-		base64AccessToken := base64.StdEncoding.EncodeToString([]byte(val))
-		config.accessToken = base64AccessToken
-	} else {
-		val = os.Getenv(envOCIUsername)
-		if val == "" {
-			return ociConfig{}, fmt.Errorf("environment variable %s is not set", envOCIUsername)
-		}
-		config.username = val
+	return config, nil
+}
 
-		val = os.Getenv(envOCIPassword)
-		if val == "" {
-			return ociConfig{}, fmt.Errorf("environment variable %s is not set", envOCIPassword)
-		}
-		config.password = val
+// validateRepository checks that repo has the format <registry>/<namespace>/<repository>, like remote.NewRepository
+// expects it, and explains common mistakes like a scheme or a tag.
+func validateRepository(repo string) error {
+	if scheme, rest, found := strings.Cut(repo, "://"); found {
+		return fmt.Errorf("environment variable %s must not contain a scheme, got %q. Use %q instead of %s://", envOCIRepository, repo, rest, scheme)
 	}
 
-	return config, nil
+	ref, err := registry.ParseReference(repo)
+	if err != nil {
+		return fmt.Errorf("environment variable %s has invalid value %q, expected <registry>/<namespace>/<repository>, e.g. registry.example.com/foo/cluster-stacks: %w", envOCIRepository, repo, err)
+	}
+
+	if ref.Reference != "" {
+		return fmt.Errorf("environment variable %s must not contain a tag or digest, got %q. The tag is the name of the release, use %q", envOCIRepository, repo, ref.Registry+"/"+ref.Repository)
+	}
+
+	return nil
 }
 
 func newOCIConfigWithoutRepository() (ociConfig, error) {
 	var config ociConfig
 
-	val := os.Getenv(envOCIRegistry)
-	if val == "" {
-		return ociConfig{}, fmt.Errorf("environment variable %s is not set", envOCIRegistry)
-	}
-	config.registry = val
+	// The registry is only needed to scope credentials from the environment. newClient defaults it to the
+	// registry of the repository.
+	config.registry = os.Getenv(envOCIRegistry)
 
-	val = os.Getenv(envOCIAccessToken)
+	// Credentials from the environment are optional. If none are set, the docker config is used.
+	val := os.Getenv(envOCIAccessToken)
 	if val != "" {
 		base64AccessToken := base64.StdEncoding.EncodeToString([]byte(val))
+		logging.AddSecret(val, base64AccessToken)
 		config.accessToken = base64AccessToken
-	} else {
+	} else if os.Getenv(envOCIUsername) != "" || os.Getenv(envOCIPassword) != "" {
 		val = os.Getenv(envOCIUsername)
 		if val == "" {
 			return ociConfig{}, fmt.Errorf("environment variable %s is not set", envOCIUsername)
@@ -99,7 +113,67 @@ func newOCIConfigWithoutRepository() (ociConfig, error) {
 			return ociConfig{}, fmt.Errorf("environment variable %s is not set", envOCIPassword)
 		}
 		config.password = val
+		logging.AddSecret(val)
+	}
+
+	plainHTTP, err := getPlainHTTP()
+	if err != nil {
+		return ociConfig{}, err
 	}
+	config.plainHTTP = plainHTTP
 
 	return config, nil
 }
+
+func getPlainHTTP() (bool, error) {
+	val := os.Getenv(envOCIPlainHTTP)
+	if val == "" {
+		return false, nil
+	}
+
+	plainHTTP, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, fmt.Errorf("environment variable %s has invalid value %q: %w", envOCIPlainHTTP, val, err)
+	}
+
+	return plainHTTP, nil
+}
+
+// credential returns the credentials from the environment if they are set.
+// Otherwise, the credentials are read from the docker config, including credential helpers.
+// If the docker config has no credentials for the registry either, requests are anonymous,
+// which is enough to pull from public repositories.
+func (c ociConfig) credential() auth.CredentialFunc {
+	if c.accessToken != "" || c.username != "" {
+		return auth.StaticCredential(c.registry, auth.Credential{
+			AccessToken: c.accessToken,
+			Username:    c.username,
+			Password:    c.password,
+		})
+	}
+
+	return dockerCredential
+}
+
+// dockerCredential reads the credentials of the registry from the docker config, e.g. ~/.docker/config.json.
+// Credential helpers configured in the docker config are invoked.
+func dockerCredential(_ context.Context, hostport string) (auth.Credential, error) {
+	configFile, err := dockerconfig.Load(dockerconfig.Dir())
+	if err != nil {
+		return auth.EmptyCredential, fmt.Errorf("failed to load docker config: %w", err)
+	}
+
+	authConfig, err := configFile.GetAuthConfig(hostport)
+	if err != nil {
+		return auth.EmptyCredential, fmt.Errorf("failed to get credentials for %s from docker config: %w", hostport, err)
+	}
+
+	logging.AddSecret(authConfig.Password, authConfig.IdentityToken, authConfig.RegistryToken)
+
+	return auth.Credential{
+		Username:     authConfig.Username,
+		Password:     authConfig.Password,
+		RefreshToken: authConfig.IdentityToken,
+		AccessToken:  authConfig.RegistryToken,
+	}, nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SovereignCloudStack/csctl/pkg/logging"
+)
+
+func TestNewOCIConfigRepository(t *testing.T) {
+	tests := []struct {
+		name       string
+		repository string
+		wantErr    string
+	}{
+		{
+			name:       "valid",
+			repository: "registry.example.com/foo/cluster-stacks",
+		},
+		{
+			name:       "registry with port",
+			repository: "localhost:5000/cluster-stacks",
+		},
+		{
+			name:    "not set",
+			wantErr: "OCI_REPOSITORY is not set",
+		},
+		{
+			name:       "tag",
+			repository: "registry.example.com/foo:bar",
+			wantErr:    `must not contain a tag or digest, got "registry.example.com/foo:bar". The tag is the name of the release, use "registry.example.com/foo"`,
+		},
+		{
+			name:       "digest",
+			repository: "registry.example.com/foo@sha256:0000000000000000000000000000000000000000000000000000000000000000",
+			wantErr:    "must not contain a tag or digest",
+		},
+		{
+			name:       "oci scheme",
+			repository: "oci://registry.example.com/foo/cluster-stacks",
+			wantErr:    `must not contain a scheme, got "oci://registry.example.com/foo/cluster-stacks". Use "registry.example.com/foo/cluster-stacks" instead of oci://`,
+		},
+		{
+			name:       "https scheme",
+			repository: "https://registry.example.com/foo",
+			wantErr:    "must not contain a scheme",
+		},
+		{
+			name:       "uppercase repository",
+			repository: "registry.example.com/Foo",
+			wantErr:    "has invalid value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(envOCIRepository, tt.repository)
+			t.Setenv(envOCIAccessToken, "")
+			t.Setenv(envOCIUsername, "")
+			t.Setenv(envOCIPassword, "")
+			t.Setenv(envOCIPlainHTTP, "")
+
+			config, err := newOCIConfig()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if config.repository != tt.repository {
+					t.Errorf("repository = %q, want %q", config.repository, tt.repository)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("newOCIConfig() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewOCIConfigRedactsCredentials(t *testing.T) {
+	const (
+		password    = "oci-s3cr3t-password"
+		accessToken = "oci-s3cr3t-token"
+	)
+
+	t.Setenv(envOCIRepository, "registry.example.com/foo/cluster-stacks")
+	t.Setenv(envOCIPlainHTTP, "")
+	t.Setenv(envOCIUsername, "user")
+	t.Setenv(envOCIPassword, password)
+	t.Setenv(envOCIAccessToken, "")
+	if _, err := newOCIConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(envOCIAccessToken, accessToken)
+	config, err := newOCIConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The access token is sent base64 encoded, so that form must not show up either.
+	for _, secret := range []string{password, accessToken, config.accessToken} {
+		if got := logging.Redact("credential " + secret); strings.Contains(got, secret) {
+			t.Errorf("Redact() = %q, want the secret to be redacted", got)
+		}
+	}
+}
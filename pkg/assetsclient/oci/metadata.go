@@ -0,0 +1,138 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/SovereignCloudStack/csctl/pkg/assetsclient"
+	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/sync/errgroup"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+)
+
+// listMetadataConcurrency is the maximum number of manifests fetched at the same time by ListReleaseWithMetadata.
+const listMetadataConcurrency = 8
+
+var _ = assetsclient.MetadataGetter(&Client{})
+
+// GetReleaseMetadata returns the annotations of the manifest of the specified release.
+// Only the manifest is fetched, not the release assets.
+func (c *Client) GetReleaseMetadata(ctx context.Context, tag string) (map[string]string, error) {
+	manifest, err := c.fetchManifest(ctx, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest.Annotations, nil
+}
+
+// GetReleaseArtifactType returns the artifact type of the manifest of the specified release.
+func (c *Client) GetReleaseArtifactType(ctx context.Context, tag string) (string, error) {
+	manifest, err := c.fetchManifest(ctx, tag)
+	if err != nil {
+		return "", err
+	}
+
+	return manifest.ArtifactType, nil
+}
+
+// ListReleaseWithMetadata returns the releases in the repository for which match returns true, or all releases
+// if match is nil, together with the annotations of their manifests. Only the manifests of matching releases are
+// fetched, concurrently. If the manifest of a release can't be fetched, the error is returned in its Err.
+func (c *Client) ListReleaseWithMetadata(ctx context.Context, match func(tag string) bool) ([]assetsclient.ReleaseWithMetadata, error) {
+	tags, err := c.ListRelease(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if match != nil {
+		tags = slices.DeleteFunc(tags, func(tag string) bool { return !match(tag) })
+	}
+
+	releases := make([]assetsclient.ReleaseWithMetadata, len(tags))
+
+	var g errgroup.Group
+	g.SetLimit(listMetadataConcurrency)
+
+	for i, tag := range tags {
+		i, tag := i, tag
+		g.Go(func() error {
+			annotations, err := c.GetReleaseMetadata(ctx, tag)
+			releases[i] = assetsclient.ReleaseWithMetadata{
+				Tag:         tag,
+				Annotations: annotations,
+				Err:         err,
+			}
+			return nil
+		})
+	}
+
+	_ = g.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get metadata of releases: %w", err)
+	}
+
+	return releases, nil
+}
+
+// GetReleaseFile returns the content of the release asset fileName of the specified release.
+// If the asset is larger than maxSize, nil is returned without fetching it.
+func (c *Client) GetReleaseFile(ctx context.Context, tag, fileName string, maxSize int64) ([]byte, error) {
+	manifest, err := c.fetchManifest(ctx, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layer := range manifest.Layers {
+		if layer.Annotations[imagev1.AnnotationTitle] != fileName {
+			continue
+		}
+
+		if layer.Size > maxSize {
+			return nil, nil
+		}
+
+		data, err := content.FetchAll(ctx, c.Repository, layer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s of release %q: %w", fileName, tag, err)
+		}
+
+		return data, nil
+	}
+
+	return nil, fmt.Errorf("release %q does not contain %s", tag, fileName)
+}
+
+func (c *Client) fetchManifest(ctx context.Context, tag string) (*imagev1.Manifest, error) {
+	_, data, err := oras.FetchBytes(ctx, c.Repository, tag, oras.DefaultFetchBytesOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest of release %q: %w", tag, err)
+	}
+
+	manifest := &imagev1.Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal manifest of release %q: %w", tag, err)
+	}
+
+	return manifest, nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+// fakeRegistry serves the tags and manifests of the repository "stacks". Manifests of tags in broken fail.
+type fakeRegistry struct {
+	tags   []string
+	broken map[string]bool
+
+	mu      sync.Mutex
+	fetched []string
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/v2/stacks/tags/list" {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"name": "stacks", "tags": f.tags})
+		return
+	}
+
+	tag, ok := strings.CutPrefix(r.URL.Path, "/v2/stacks/manifests/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	f.mu.Lock()
+	f.fetched = append(f.fetched, tag)
+	f.mu.Unlock()
+
+	if f.broken[tag] {
+		http.Error(w, "broken", http.StatusInternalServerError)
+		return
+	}
+
+	data, err := json.Marshal(imagev1.Manifest{
+		MediaType:   imagev1.MediaTypeImageManifest,
+		Config:      imagev1.DescriptorEmptyJSON,
+		Layers:      []imagev1.Descriptor{},
+		Annotations: map[string]string{"hash": "hash-of-" + tag},
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", imagev1.MediaTypeImageManifest)
+	w.Header().Set("Docker-Content-Digest", digest.FromBytes(data).String())
+	w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+	_, _ = w.Write(data)
+}
+
+func newTestClient(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	repository, err := remote.NewRepository(strings.TrimPrefix(server.URL, "http://") + "/stacks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repository.PlainHTTP = true
+	// The default client retries failed requests, which only slows down tests of errors.
+	repository.Client = &auth.Client{Client: server.Client()}
+
+	return &Client{Repository: repository}
+}
+
+func TestListReleaseWithMetadata(t *testing.T) {
+	registry := &fakeRegistry{
+		tags: []string{
+			"docker-ferrol-1-27-v1",
+			"docker-ferrol-1-27-v2",
+			"docker-valencia-1-27-v1",
+			"not-a-release",
+		},
+		broken: map[string]bool{"docker-ferrol-1-27-v2": true},
+	}
+	client := newTestClient(t, registry)
+
+	match := func(tag string) bool { return strings.HasPrefix(tag, "docker-ferrol-") }
+
+	releases, err := client.ListReleaseWithMetadata(context.Background(), match)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(releases) != 2 {
+		t.Fatalf("got %d releases, want 2: %v", len(releases), releases)
+	}
+
+	if releases[0].Tag != "docker-ferrol-1-27-v1" || releases[0].Err != nil || releases[0].Annotations["hash"] != "hash-of-docker-ferrol-1-27-v1" {
+		t.Errorf("unexpected release %+v", releases[0])
+	}
+
+	if releases[1].Tag != "docker-ferrol-1-27-v2" || releases[1].Err == nil {
+		t.Errorf("expected an error for the broken release, got %+v", releases[1])
+	}
+
+	for _, tag := range registry.fetched {
+		if !match(tag) {
+			t.Errorf("manifest of %s was fetched, although it does not match", tag)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"io"
+
+	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+)
+
+// ProgressFunc is called while a file of a release is transferred with its name, the number of bytes
+// transferred so far and its size. It is called concurrently for different files.
+type ProgressFunc func(name string, transferred, total int64)
+
+// withProgress returns src, which reports the progress of reading release assets to the ProgressFunc of the client.
+func (c *Client) withProgress(src oras.ReadOnlyTarget) oras.ReadOnlyTarget {
+	if c.progress == nil {
+		return src
+	}
+
+	return &progressTarget{ReadOnlyTarget: src, progress: c.progress}
+}
+
+// progressTarget reports the progress of fetching the release assets, i.e. the blobs with a file name.
+// Manifests are small and are not reported.
+type progressTarget struct {
+	oras.ReadOnlyTarget
+	progress ProgressFunc
+}
+
+func (t *progressTarget) Fetch(ctx context.Context, desc imagev1.Descriptor) (io.ReadCloser, error) {
+	rc, err := t.ReadOnlyTarget.Fetch(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+
+	name := desc.Annotations[imagev1.AnnotationTitle]
+	if name == "" {
+		return rc, nil
+	}
+
+	t.progress(name, 0, desc.Size)
+
+	return &progressReader{ReadCloser: rc, name: name, total: desc.Size, progress: t.progress}, nil
+}
+
+type progressReader struct {
+	io.ReadCloser
+	name        string
+	transferred int64
+	total       int64
+	progress    ProgressFunc
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.transferred += int64(n)
+		r.progress(r.name, r.transferred, r.total)
+	}
+
+	return n, err
+}
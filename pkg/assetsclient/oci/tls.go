@@ -0,0 +1,67 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// TLSOptions configures the TLS connection to OCI registries.
+type TLSOptions struct {
+	// InsecureSkipVerify disables the verification of the certificate of the registry.
+	InsecureSkipVerify bool
+	// CACertFile is a PEM file with additional CA certificates to trust.
+	CACertFile string
+}
+
+// newHTTPClient returns the HTTP client used to talk to the registry with the given transport.
+func (o TLSOptions) newHTTPClient(transport *http.Transport) (*http.Client, error) {
+	if !o.InsecureSkipVerify && o.CACertFile == "" {
+		return &http.Client{Transport: transport}, nil
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: o.InsecureSkipVerify, // #nosec G402
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if o.CACertFile != "" {
+		pem, err := os.ReadFile(filepath.Clean(o.CACertFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("failed to find any PEM certificate in %s", o.CACertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{Transport: transport}, nil
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTLSOptions(t *testing.T) {
+	server := httptest.NewTLSServer(&fakeRegistry{})
+	t.Cleanup(server.Close)
+
+	caCertFile := filepath.Join(t.TempDir(), "ca.pem")
+	caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caCertFile, caCert, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := strings.TrimPrefix(server.URL, "https://") + "/stacks"
+
+	tests := []struct {
+		name    string
+		opts    TLSOptions
+		wantErr bool
+	}{
+		{
+			name:    "self-signed certificate is not trusted by default",
+			wantErr: true,
+		},
+		{
+			name: "CA certificate",
+			opts: TLSOptions{CACertFile: caCertFile},
+		},
+		{
+			name: "insecure skip verify",
+			opts: TLSOptions{InsecureSkipVerify: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := newClient(repo, ociConfig{}, Options{TLS: tt.opts})
+			if err != nil {
+				t.Fatalf("failed to create client: %v", err)
+			}
+
+			found, err := client.FoundRelease(context.Background(), "docker-ferrol-1-27-v1")
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "certificate") {
+					t.Fatalf("expected a certificate error, got %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !found {
+				t.Error("expected the release to be found")
+			}
+		})
+	}
+}
+
+func TestTLSOptionsInvalidCACertFile(t *testing.T) {
+	caCertFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caCertFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := newClient("registry.example.com/stacks", ociConfig{}, Options{TLS: TLSOptions{CACertFile: caCertFile}}); err == nil {
+		t.Fatal("expected an error for a file without certificates")
+	}
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cache caches downloaded releases across invocations of csctl.
+// Releases are stored by a key that identifies the release content, e.g. the digest of an OCI manifest.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// checksumsFileName is the file in each cache entry with the sha256 checksums of the cached files.
+const checksumsFileName = ".checksums.json"
+
+// Cache is a content-addressed cache of release directories.
+type Cache struct {
+	dir string
+}
+
+// New returns the cache in $XDG_CACHE_HOME/csctl, or the equivalent of the operating system.
+func New() (*Cache, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cache directory: %w", err)
+	}
+
+	return &Cache{dir: filepath.Join(dir, "csctl")}, nil
+}
+
+// Dir returns the directory of the cache.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Get copies the cached release with the given key to dst. It returns false if the release is not cached.
+// Entries that fail the integrity check are removed and reported as not cached.
+func (c *Cache) Get(key, dst string) (bool, error) {
+	entry := c.entryDir(key)
+
+	checksums, err := readChecksums(entry)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, c.remove(entry, err)
+	}
+
+	for name, checksum := range checksums {
+		actual, err := fileChecksum(filepath.Join(entry, name))
+		if err != nil || actual != checksum {
+			return false, c.remove(entry, fmt.Errorf("cached file %s is corrupt", name))
+		}
+	}
+
+	if err := os.MkdirAll(dst, os.ModePerm); err != nil {
+		return false, fmt.Errorf("failed to create directory %s: %w", dst, err)
+	}
+
+	for name := range checksums {
+		if err := copyFile(filepath.Join(entry, name), filepath.Join(dst, name)); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// Put stores the files of the release directory src with the given key.
+func (c *Cache) Put(key, src string) error {
+	entry := c.entryDir(key)
+
+	files, err := os.ReadDir(src)
+	if err != nil {
+		return fmt.Errorf("failed to read directory %s: %w", src, err)
+	}
+
+	// Write into a temporary directory first, so that incomplete entries are never used.
+	if err := os.MkdirAll(c.dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create cache directory %s: %w", c.dir, err)
+	}
+	tmp, err := os.MkdirTemp(c.dir, "tmp-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary cache directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	checksums := make(map[string]string, len(files))
+	for _, file := range files {
+		if !file.Type().IsRegular() {
+			continue
+		}
+
+		if err := copyFile(filepath.Join(src, file.Name()), filepath.Join(tmp, file.Name())); err != nil {
+			return err
+		}
+
+		checksums[file.Name()], err = fileChecksum(filepath.Join(tmp, file.Name()))
+		if err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(checksums, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal checksums: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(tmp, checksumsFileName), data, os.FileMode(0o644)); err != nil {
+		return fmt.Errorf("failed to write checksums: %w", err)
+	}
+
+	if err := os.RemoveAll(entry); err != nil {
+		return fmt.Errorf("failed to remove cache entry %s: %w", entry, err)
+	}
+
+	if err := os.Rename(tmp, entry); err != nil {
+		return fmt.Errorf("failed to move cache entry to %s: %w", entry, err)
+	}
+
+	return nil
+}
+
+// Clean removes all cached releases.
+func (c *Cache) Clean() error {
+	if err := os.RemoveAll(c.dir); err != nil {
+		return fmt.Errorf("failed to remove cache directory %s: %w", c.dir, err)
+	}
+
+	return nil
+}
+
+func (c *Cache) entryDir(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
+
+// remove removes a broken cache entry. The cause is returned together with errors during the removal.
+func (c *Cache) remove(entry string, cause error) error {
+	slog.Warn("Removing invalid cache entry", "path", entry, "error", cause)
+
+	if err := os.RemoveAll(entry); err != nil {
+		return errors.Join(cause, fmt.Errorf("failed to remove cache entry %s: %w", entry, err))
+	}
+
+	return nil
+}
+
+func readChecksums(entry string) (map[string]string, error) {
+	data, err := os.ReadFile(filepath.Join(entry, checksumsFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checksums: %w", err)
+	}
+
+	var checksums map[string]string
+	if err := json.Unmarshal(data, &checksums); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal checksums: %w", err)
+	}
+
+	return checksums, nil
+}
+
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func copyFile(src, dst string) (reterr error) {
+	in, err := os.Open(filepath.Clean(src))
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(filepath.Clean(dst))
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+	defer func() {
+		if err := out.Close(); err != nil && reterr == nil {
+			reterr = fmt.Errorf("failed to close %s: %w", dst, err)
+		}
+	}()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return fmt.Errorf("failed to copy %s to %s: %w", src, dst, err)
+	}
+
+	return nil
+}
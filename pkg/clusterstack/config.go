@@ -18,10 +18,12 @@ limitations under the License.
 package clusterstack
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -30,25 +32,79 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+var (
+	providerTypeRegex      = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	kubernetesVersionRegex = regexp.MustCompile(`^v\d+\.\d+\.\d+$`)
+	// providerAPIVersionRegex matches the apiVersion of the provider config, e.g. docker.csctl.clusterstack.x-k8s.io/v1alpha1.
+	providerAPIVersionRegex = regexp.MustCompile(`^([a-z0-9]([-a-z0-9]*[a-z0-9])?)\.csctl\.clusterstack\.x-k8s\.io/v\d+((alpha|beta)\d+)?$`)
+)
+
+// EnvExtraAPIVersions is the environment variable with a comma-separated list of apiVersions of csctl.yaml
+// that are accepted in addition to SupportedAPIVersions.
+const EnvExtraAPIVersions = "CSCTL_EXTRA_API_VERSIONS"
+
+// SupportedAPIVersions are the apiVersions of csctl.yaml that csctl understands.
+var SupportedAPIVersions = []string{"csctl.clusterstack.x-k8s.io/v1alpha1"}
+
 // CsctlConfig contains information of CsctlConfig yaml.
 type CsctlConfig struct {
-	APIVersion string `yaml:"apiVersion"`
+	APIVersion string `yaml:"apiVersion" json:"apiVersion"`
 	Config     struct {
-		KubernetesVersion string `yaml:"kubernetesVersion"`
-		ClusterStackName  string `yaml:"clusterStackName"`
-		Provider          struct {
-			Type       string                 `yaml:"type"`
-			APIVersion string                 `yaml:"apiVersion"`
-			Config     map[string]interface{} `yaml:"config"`
-		} `yaml:"provider"`
-	} `yaml:"config"`
+		KubernetesVersion string `yaml:"kubernetesVersion" json:"kubernetesVersion"`
+		// KubernetesVersions is a list of Kubernetes versions, for each of which a release is created.
+		// It is an alternative to KubernetesVersion. Use WithKubernetesVersion to get the config of one release.
+		KubernetesVersions []string `yaml:"kubernetesVersions,omitempty" json:"kubernetesVersions,omitempty"`
+		ClusterStackName   string   `yaml:"clusterStackName" json:"clusterStackName"`
+		Provider           struct {
+			Type       string         `yaml:"type" json:"type"`
+			APIVersion string         `yaml:"apiVersion" json:"apiVersion"`
+			Config     ProviderConfig `yaml:"config" json:"config"`
+		} `yaml:"provider" json:"provider"`
+	} `yaml:"config" json:"config"`
+}
+
+const (
+	// ProviderConfigMethodGet means that the provider plugin gets existing node images.
+	ProviderConfigMethodGet = "get"
+	// ProviderConfigMethodBuild means that the provider plugin builds the node images.
+	ProviderConfigMethodBuild = "build"
+)
+
+// ProviderConfig contains the provider specific configuration of the CsctlConfig yaml.
+type ProviderConfig struct {
+	Method string    `yaml:"method,omitempty" json:"method,omitempty"`
+	Images []*string `yaml:"images,omitempty" json:"images,omitempty"`
+	// Extra contains all other provider specific keys.
+	Extra map[string]interface{} `yaml:",inline" json:"extra,omitempty"`
+}
+
+// IsEmpty returns true if there is no provider specific configuration.
+func (p *ProviderConfig) IsEmpty() bool {
+	return p.Method == "" && len(p.Images) == 0 && len(p.Extra) == 0
 }
 
 // GetCsctlConfig returns CsctlConfig.
 func GetCsctlConfig(path string) (*CsctlConfig, error) {
+	cs, err := ParseCsctlConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cs.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cs, nil
+}
+
+// ParseCsctlConfig reads the csctl.yaml in path without validating it.
+func ParseCsctlConfig(path string) (*CsctlConfig, error) {
 	configPath := filepath.Join(path, "csctl.yaml")
 	configFileData, err := os.ReadFile(filepath.Clean(configPath))
 	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, missingCsctlConfigError(path, err)
+		}
 		return nil, fmt.Errorf("failed to read csctl config: %w", err)
 	}
 
@@ -57,36 +113,137 @@ func GetCsctlConfig(path string) (*CsctlConfig, error) {
 		return nil, fmt.Errorf("failed to unmarshal csctl yaml: %w", err)
 	}
 
-	if cs.Config.Provider.Type == "" {
-		return nil, fmt.Errorf("provider type must not be empty")
+	return cs, nil
+}
+
+// missingCsctlConfigError returns an error that explains the expected layout if path contains no csctl.yaml.
+// It mentions immediate subdirectories that contain a csctl.yaml, because pointing at a parent directory is a common mistake.
+func missingCsctlConfigError(path string, err error) error {
+	msg := "the argument must be the cluster stack directory, which contains csctl.yaml, " +
+		"the cluster-class and cluster-addon directories and clusteraddon.yaml or cluster-addon-values.yaml"
+
+	candidates, globErr := filepath.Glob(filepath.Join(path, "*", "csctl.yaml"))
+	if globErr == nil && len(candidates) > 0 {
+		dirs := make([]string, 0, len(candidates))
+		for _, candidate := range candidates {
+			dirs = append(dirs, filepath.Dir(candidate))
+		}
+		msg += fmt.Sprintf(". Found csctl.yaml in the subdirectories %s, did you mean one of them?", strings.Join(dirs, ", "))
 	}
 
-	if len(cs.Config.Provider.Type) > 253 {
-		return nil, fmt.Errorf("provider name must not be greater than 253")
+	return fmt.Errorf("no csctl.yaml found in %s (%w), %s", path, err, msg)
+}
+
+// Validate validates the CsctlConfig. All problems are returned at once, joined with errors.Join.
+func (c *CsctlConfig) Validate() error {
+	var errs []error
+
+	if err := validateAPIVersion(c.APIVersion); err != nil {
+		errs = append(errs, err)
 	}
 
-	match, err := regexp.MatchString(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`, cs.Config.Provider.Type)
-	if err != nil {
-		return nil, fmt.Errorf("failed to provider name match regex: %w", err)
+	if c.Config.Provider.Type == "" {
+		errs = append(errs, fmt.Errorf("provider type must not be empty"))
+	} else if len(c.Config.Provider.Type) > 253 {
+		errs = append(errs, fmt.Errorf("provider name must not be greater than 253"))
+	} else if !providerTypeRegex.MatchString(c.Config.Provider.Type) {
+		errs = append(errs, fmt.Errorf("invalid provider type: %q", c.Config.Provider.Type))
+	}
+
+	if err := c.validateProviderAPIVersion(); err != nil {
+		errs = append(errs, err)
 	}
-	if !match {
-		return nil, fmt.Errorf("invalid provider type: %q", cs.Config.Provider.Type)
+
+	method := c.Config.Provider.Config.Method
+	if method != "" && method != ProviderConfigMethodGet && method != ProviderConfigMethodBuild {
+		errs = append(errs, fmt.Errorf("invalid provider config method %q: must be %q or %q", method, ProviderConfigMethodGet, ProviderConfigMethodBuild))
 	}
 
-	if cs.Config.ClusterStackName == "" {
-		return nil, fmt.Errorf("cluster stack name must not be empty")
+	if c.Config.ClusterStackName == "" {
+		errs = append(errs, fmt.Errorf("cluster stack name must not be empty"))
 	}
 
-	// Validate kubernetes version
-	matched, err := regexp.MatchString(`^v\d+\.\d+\.\d+$`, cs.Config.KubernetesVersion)
-	if err != nil {
-		return nil, fmt.Errorf("failed to kubernetes match regex: %w", err)
+	switch {
+	case len(c.Config.KubernetesVersions) > 0 && c.Config.KubernetesVersion != "":
+		errs = append(errs, fmt.Errorf("only one of kubernetesVersion and kubernetesVersions must be set"))
+	case len(c.Config.KubernetesVersions) > 0:
+		seen := map[string]bool{}
+		for _, kubernetesVersion := range c.Config.KubernetesVersions {
+			if !kubernetesVersionRegex.MatchString(kubernetesVersion) {
+				errs = append(errs, fmt.Errorf("invalid kubernetes version: %q", kubernetesVersion))
+			} else if seen[kubernetesVersion] {
+				errs = append(errs, fmt.Errorf("duplicate kubernetes version: %q", kubernetesVersion))
+			}
+			seen[kubernetesVersion] = true
+		}
+	case !kubernetesVersionRegex.MatchString(c.Config.KubernetesVersion):
+		errs = append(errs, fmt.Errorf("invalid kubernetes version: %q", c.Config.KubernetesVersion))
 	}
-	if !matched {
-		return nil, fmt.Errorf("invalid kubernetes version: %q", cs.Config.KubernetesVersion)
+
+	return errors.Join(errs...)
+}
+
+// validateAPIVersion checks that apiVersion is one of SupportedAPIVersions or of the versions in EnvExtraAPIVersions.
+func validateAPIVersion(apiVersion string) error {
+	allowed := slices.Clone(SupportedAPIVersions)
+	for _, extra := range strings.Split(os.Getenv(EnvExtraAPIVersions), ",") {
+		if extra = strings.TrimSpace(extra); extra != "" {
+			allowed = append(allowed, extra)
+		}
 	}
 
-	return cs, nil
+	if slices.Contains(allowed, apiVersion) {
+		return nil
+	}
+
+	if apiVersion == "" {
+		return fmt.Errorf("apiVersion must not be empty, supported versions are: %s", strings.Join(allowed, ", "))
+	}
+
+	return fmt.Errorf("unsupported apiVersion %q, supported versions are: %s. Set %s to accept other versions",
+		apiVersion, strings.Join(allowed, ", "), EnvExtraAPIVersions)
+}
+
+// validateProviderAPIVersion checks that the apiVersion of the provider config has the format
+// <provider>.csctl.clusterstack.x-k8s.io/<version> and belongs to the provider type. An empty apiVersion is accepted.
+func (c *CsctlConfig) validateProviderAPIVersion() error {
+	apiVersion := c.Config.Provider.APIVersion
+	if apiVersion == "" {
+		return nil
+	}
+
+	match := providerAPIVersionRegex.FindStringSubmatch(apiVersion)
+	if match == nil {
+		return fmt.Errorf("invalid provider apiVersion %q: must have the format <provider>.csctl.clusterstack.x-k8s.io/<version>, e.g. %s.csctl.clusterstack.x-k8s.io/v1alpha1",
+			apiVersion, c.Config.Provider.Type)
+	}
+
+	if match[1] != c.Config.Provider.Type {
+		return fmt.Errorf("provider apiVersion %q does not belong to provider type %q", apiVersion, c.Config.Provider.Type)
+	}
+
+	return nil
+}
+
+// GetKubernetesVersions returns the Kubernetes versions of the config, for each of which a release is created.
+func (c *CsctlConfig) GetKubernetesVersions() []string {
+	if len(c.Config.KubernetesVersions) > 0 {
+		return slices.Clone(c.Config.KubernetesVersions)
+	}
+	return []string{c.Config.KubernetesVersion}
+}
+
+// WithKubernetesVersion returns a copy of the config with the single Kubernetes version kubernetesVersion.
+func (c *CsctlConfig) WithKubernetesVersion(kubernetesVersion string) (*CsctlConfig, error) {
+	if !kubernetesVersionRegex.MatchString(kubernetesVersion) {
+		return nil, fmt.Errorf("invalid kubernetes version: %q", kubernetesVersion)
+	}
+
+	config := *c
+	config.Config.KubernetesVersion = kubernetesVersion
+	config.Config.KubernetesVersions = nil
+
+	return &config, nil
 }
 
 // ParseKubernetesVersion parse the kubernetes version present in the Csctl Config.
@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterstack
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ValidateLayout checks that the directories and files required to build the cluster stack in path exist.
+// The required files depend on the convention: cluster stacks with clusteraddon.yaml need at least one chart
+// in a subdirectory of cluster-addon, the others need cluster-addon-values.yaml and a cluster-addon chart.
+// All problems are returned at once, joined with errors.Join.
+func ValidateLayout(path string) error {
+	var errs []error
+
+	if err := requireFile(filepath.Join(path, "cluster-class", "Chart.yaml")); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := requireDir(filepath.Join(path, "cluster-addon")); err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+
+	if _, err := os.Stat(filepath.Join(path, "clusteraddon.yaml")); err == nil {
+		chartYamls, err := filepath.Glob(filepath.Join(path, "cluster-addon", "*", "Chart.yaml"))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("glob for charts in %s failed: %w", filepath.Join(path, "cluster-addon"), err))
+		} else if len(chartYamls) == 0 {
+			errs = append(errs, fmt.Errorf("%s is missing: clusteraddon.yaml requires at least one cluster addon chart", filepath.Join(path, "cluster-addon", "<name>", "Chart.yaml")))
+		}
+		return errors.Join(errs...)
+	}
+
+	if err := requireFile(filepath.Join(path, "cluster-addon-values.yaml")); err != nil {
+		errs = append(errs, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(path, "cluster-addon", "Chart.yaml")); err != nil {
+		chartYamls, err := filepath.Glob(filepath.Join(path, "cluster-addon", "*", "Chart.yaml"))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("glob for charts in %s failed: %w", filepath.Join(path, "cluster-addon"), err))
+		} else if len(chartYamls) == 0 {
+			errs = append(errs, fmt.Errorf("%s is missing: neither clusteraddon.yaml nor a cluster addon chart found", filepath.Join(path, "cluster-addon", "Chart.yaml")))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func requireFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%s is missing", path)
+		}
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	if info.IsDir() {
+		return fmt.Errorf("%s must be a file, not a directory", path)
+	}
+
+	return nil
+}
+
+func requireDir(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("directory %s is missing", path)
+		}
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("%s must be a directory", path)
+	}
+
+	return nil
+}
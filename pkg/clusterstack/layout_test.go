@@ -0,0 +1,128 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterstack
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateLayout(t *testing.T) {
+	tests := []struct {
+		name         string
+		clusterStack string
+		modify       func(path string) error
+		wantErrs     []string
+	}{
+		{
+			name:         "legacy cluster stack",
+			clusterStack: "ferrol",
+		},
+		{
+			name:         "cluster stack with clusteraddon.yaml",
+			clusterStack: "valencia",
+		},
+		{
+			name:         "missing cluster-class",
+			clusterStack: "valencia",
+			modify: func(path string) error {
+				return os.RemoveAll(filepath.Join(path, "cluster-class"))
+			},
+			wantErrs: []string{filepath.Join("cluster-class", "Chart.yaml") + " is missing"},
+		},
+		{
+			name:         "cluster-class is a file",
+			clusterStack: "ferrol",
+			modify: func(path string) error {
+				if err := os.RemoveAll(filepath.Join(path, "cluster-class")); err != nil {
+					return err
+				}
+				return os.WriteFile(filepath.Join(path, "cluster-class"), nil, 0o600)
+			},
+			wantErrs: []string{filepath.Join("cluster-class", "Chart.yaml")},
+		},
+		{
+			name:         "missing cluster-class and cluster-addon-values.yaml",
+			clusterStack: "ferrol",
+			modify: func(path string) error {
+				if err := os.RemoveAll(filepath.Join(path, "cluster-class")); err != nil {
+					return err
+				}
+				return os.Remove(filepath.Join(path, "cluster-addon-values.yaml"))
+			},
+			wantErrs: []string{
+				filepath.Join("cluster-class", "Chart.yaml") + " is missing",
+				"cluster-addon-values.yaml is missing",
+			},
+		},
+		{
+			name:         "missing cluster-addon",
+			clusterStack: "ferrol",
+			modify: func(path string) error {
+				return os.RemoveAll(filepath.Join(path, "cluster-addon"))
+			},
+			wantErrs: []string{"cluster-addon"},
+		},
+		{
+			name:         "clusteraddon.yaml without charts",
+			clusterStack: "valencia",
+			modify: func(path string) error {
+				if err := os.RemoveAll(filepath.Join(path, "cluster-addon")); err != nil {
+					return err
+				}
+				return os.Mkdir(filepath.Join(path, "cluster-addon"), 0o750)
+			},
+			wantErrs: []string{"clusteraddon.yaml requires at least one cluster addon chart"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.clusterStack)
+			if err := os.Mkdir(path, 0o750); err != nil {
+				t.Fatal(err)
+			}
+			if err := copyDir(filepath.Join("..", "..", "tests", "cluster-stacks", "docker", tt.clusterStack), path); err != nil {
+				t.Fatal(err)
+			}
+			if tt.modify != nil {
+				if err := tt.modify(path); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := ValidateLayout(path)
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not contain %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -26,21 +26,29 @@ import (
 
 // Component contains component.
 type Component struct {
-	ClusterAddon string `yaml:"clusterAddon"`
-	NodeImage    string `yaml:"nodeImage,omitempty"`
+	ClusterAddon string `yaml:"clusterAddon" json:"clusterAddon"`
+	NodeImage    string `yaml:"nodeImage,omitempty" json:"nodeImage,omitempty"`
 }
 
 // Versions contains version information.
 type Versions struct {
-	ClusterStack string    `yaml:"clusterStack"`
-	Kubernetes   string    `yaml:"kubernetes"`
-	Components   Component `yaml:"components"`
+	ClusterStack string    `yaml:"clusterStack" json:"clusterStack"`
+	Kubernetes   string    `yaml:"kubernetes" json:"kubernetes"`
+	Components   Component `yaml:"components" json:"components"`
+}
+
+// BuildInfo contains information about the csctl that built the release.
+type BuildInfo struct {
+	CsctlVersion string `yaml:"csctlVersion,omitempty" json:"csctlVersion,omitempty"`
+	GitCommit    string `yaml:"gitCommit,omitempty" json:"gitCommit,omitempty"`
+	BuildDate    string `yaml:"buildDate,omitempty" json:"buildDate,omitempty"`
 }
 
 // MetaData contains metadata.
 type MetaData struct {
-	APIVersion string   `yaml:"apiVersion"`
-	Versions   Versions `yaml:"versions"`
+	APIVersion string     `yaml:"apiVersion" json:"apiVersion"`
+	Versions   Versions   `yaml:"versions" json:"versions"`
+	BuildInfo  *BuildInfo `yaml:"buildInfo,omitempty" json:"buildInfo,omitempty"`
 }
 
 // ParseMetaData parse the metadata file.
@@ -0,0 +1,370 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterstack
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+const (
+	clusterAddonConfigAPIVersion = "clusteraddonconfig.x-k8s.io/v1alpha1"
+	clusterAddonVersion          = "clusteraddons.clusterstack.x-k8s.io/v1alpha1"
+)
+
+// ClusterAddonConfig is the content of clusteraddon.yaml, which configures when the cluster addons are applied.
+type ClusterAddonConfig struct {
+	APIVersion          string                         `yaml:"apiVersion"`
+	ClusterAddonVersion string                         `yaml:"clusterAddonVersion"`
+	AddonStages         map[string][]ClusterAddonStage `yaml:"addonStages"`
+}
+
+// ClusterAddonStage is a cluster addon chart that is applied in a stage, like AfterControlPlaneInitialized.
+type ClusterAddonStage struct {
+	Name   string `yaml:"name"`
+	Action string `yaml:"action"`
+}
+
+// clusterAddonValues is the content of cluster-addon-values.yaml.
+type clusterAddonValues struct {
+	Values string `yaml:"values"`
+}
+
+// MigrationAction is the kind of change of a migration.
+type MigrationAction string
+
+const (
+	// MigrationCreate creates a file.
+	MigrationCreate MigrationAction = "create"
+	// MigrationMove moves a file or directory.
+	MigrationMove MigrationAction = "move"
+	// MigrationDelete deletes a file.
+	MigrationDelete MigrationAction = "delete"
+)
+
+// MigrationChange is a change of a migration. Paths are relative to the cluster stack.
+type MigrationChange struct {
+	Action MigrationAction
+	Path   string
+	// From is the old path of moved files.
+	From string
+	// Content is the content of created files and the old content of deleted files.
+	Content []byte
+}
+
+// PlanMigration returns the changes that migrate the cluster stack in path from cluster-addon-values.yaml to
+// clusteraddon.yaml. The cluster addon chart is moved to cluster-addon/<addonName>, the values of
+// cluster-addon-values.yaml become its overwrite.yaml, and clusteraddon.yaml applies it after the control plane
+// is initialized and before each upgrade of the cluster, like cluster addons of the old convention.
+func PlanMigration(path, addonName string) ([]MigrationChange, error) {
+	if _, err := os.Stat(filepath.Join(path, "clusteraddon.yaml")); err == nil {
+		return nil, fmt.Errorf("%s already contains clusteraddon.yaml", path)
+	}
+
+	if addonName == "" || addonName != filepath.Base(addonName) || addonName == "." || addonName == ".." {
+		return nil, fmt.Errorf("invalid cluster addon name %q, it must be a directory name", addonName)
+	}
+
+	valuesData, err := os.ReadFile(filepath.Join(path, "cluster-addon-values.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cluster-addon-values.yaml: %w", err)
+	}
+
+	var values clusterAddonValues
+	if err := yaml.Unmarshal(valuesData, &values); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cluster-addon-values.yaml: %w", err)
+	}
+
+	clusterAddonDir := filepath.Join(path, "cluster-addon")
+	if _, err := os.Stat(filepath.Join(clusterAddonDir, "Chart.yaml")); err != nil {
+		return nil, fmt.Errorf("migrating is only supported for a single cluster addon chart in %s: %w", clusterAddonDir, err)
+	}
+
+	entries, err := os.ReadDir(clusterAddonDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dir: %w", err)
+	}
+
+	changes := make([]MigrationChange, 0, len(entries)+3)
+	for _, entry := range entries {
+		if entry.Name() == addonName {
+			return nil, fmt.Errorf("cluster addon name %q conflicts with %s", addonName, filepath.Join(clusterAddonDir, entry.Name()))
+		}
+		changes = append(changes, MigrationChange{
+			Action: MigrationMove,
+			Path:   filepath.Join("cluster-addon", addonName, entry.Name()),
+			From:   filepath.Join("cluster-addon", entry.Name()),
+		})
+	}
+
+	if values.Values != "" {
+		changes = append(changes, MigrationChange{
+			Action:  MigrationCreate,
+			Path:    filepath.Join("cluster-addon", addonName, "overwrite.yaml"),
+			Content: []byte(values.Values),
+		})
+	}
+
+	config := newClusterAddonConfig(addonName)
+	configData, err := marshalYAML(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal clusteraddon.yaml: %w", err)
+	}
+
+	// The generated file must be read back like it was written.
+	var readBack ClusterAddonConfig
+	if err := yaml.Unmarshal(configData, &readBack); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal generated clusteraddon.yaml: %w", err)
+	}
+	if !reflect.DeepEqual(config, readBack) {
+		return nil, fmt.Errorf("generated clusteraddon.yaml does not round-trip")
+	}
+
+	changes = append(changes,
+		MigrationChange{Action: MigrationCreate, Path: "clusteraddon.yaml", Content: configData},
+		MigrationChange{Action: MigrationDelete, Path: "cluster-addon-values.yaml", Content: valuesData},
+	)
+
+	return changes, nil
+}
+
+// ApplyMigration applies the changes of PlanMigration to the cluster stack in path and validates the result.
+// The changes are applied to a copy next to path, which replaces path only after it has been validated, so that
+// path is left as it was if the migration fails.
+func ApplyMigration(path string, changes []MigrationChange) (reterr error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path of %s: %w", path, err)
+	}
+
+	staged, err := os.MkdirTemp(filepath.Dir(path), "."+filepath.Base(path)+"-migrate-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer func() {
+		if reterr == nil {
+			return
+		}
+		if err := os.RemoveAll(staged); err != nil {
+			reterr = errors.Join(reterr, fmt.Errorf("failed to remove staging directory %s: %w", staged, err))
+		}
+	}()
+
+	if err := copyDir(path, staged); err != nil {
+		return fmt.Errorf("failed to copy %s: %w", path, err)
+	}
+
+	if err := applyChanges(staged, changes); err != nil {
+		return err
+	}
+
+	if err := validateMigration(path, staged, changes); err != nil {
+		return fmt.Errorf("migrated cluster stack is invalid: %w", err)
+	}
+
+	backup := staged + ".old"
+	if err := os.Rename(path, backup); err != nil {
+		return fmt.Errorf("failed to move %s out of the way: %w", path, err)
+	}
+
+	if err := os.Rename(staged, path); err != nil {
+		if restoreErr := os.Rename(backup, path); restoreErr != nil {
+			return errors.Join(
+				fmt.Errorf("failed to move migrated cluster stack to %s: %w", path, err),
+				fmt.Errorf("failed to restore %s from %s: %w", path, backup, restoreErr),
+			)
+		}
+		return fmt.Errorf("failed to move migrated cluster stack to %s: %w", path, err)
+	}
+
+	if err := os.RemoveAll(backup); err != nil {
+		return fmt.Errorf("migrated %s, but failed to remove the old cluster stack in %s: %w", path, backup, err)
+	}
+
+	return nil
+}
+
+func applyChanges(path string, changes []MigrationChange) error {
+	for _, change := range changes {
+		target := filepath.Join(path, change.Path)
+
+		switch change.Action {
+		case MigrationMove:
+			if err := os.MkdirAll(filepath.Dir(target), os.FileMode(0o750)); err != nil {
+				return fmt.Errorf("failed to create directory: %w", err)
+			}
+			if err := os.Rename(filepath.Join(path, change.From), target); err != nil {
+				return fmt.Errorf("failed to move %s: %w", change.From, err)
+			}
+		case MigrationCreate:
+			if err := os.WriteFile(target, change.Content, os.FileMode(0o644)); err != nil {
+				return fmt.Errorf("failed to write %s: %w", change.Path, err)
+			}
+		case MigrationDelete:
+			if err := os.Remove(target); err != nil {
+				return fmt.Errorf("failed to delete %s: %w", change.Path, err)
+			}
+		default:
+			return fmt.Errorf("unknown migration action %q for %s", change.Action, change.Path)
+		}
+	}
+
+	return nil
+}
+
+// validateMigration checks the cluster stack migrated to staged. Created files must have the content of the
+// migration, the layout must be valid and the cluster addon chart must load with the same files as the chart
+// of the cluster stack in path, apart from the created files.
+func validateMigration(path, staged string, changes []MigrationChange) error {
+	created := make(map[string]bool)
+	chartDir := ""
+	for _, change := range changes {
+		switch change.Action {
+		case MigrationCreate:
+			created[change.Path] = true
+
+			data, err := os.ReadFile(filepath.Join(staged, change.Path))
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", change.Path, err)
+			}
+			if !bytes.Equal(data, change.Content) {
+				return fmt.Errorf("content of %s differs from the migration", change.Path)
+			}
+		case MigrationMove:
+			if change.From == filepath.Join("cluster-addon", "Chart.yaml") {
+				chartDir = filepath.Dir(change.Path)
+			}
+		}
+	}
+
+	if err := ValidateLayout(staged); err != nil {
+		return err
+	}
+
+	if chartDir == "" {
+		return fmt.Errorf("migration does not move the cluster addon chart")
+	}
+
+	oldFiles, err := chartFiles(filepath.Join(path, "cluster-addon"))
+	if err != nil {
+		return err
+	}
+
+	newFiles, err := chartFiles(filepath.Join(staged, chartDir))
+	if err != nil {
+		return err
+	}
+
+	for name := range newFiles {
+		if created[filepath.Join(chartDir, name)] {
+			delete(newFiles, name)
+		}
+	}
+
+	if !maps.EqualFunc(oldFiles, newFiles, bytes.Equal) {
+		return fmt.Errorf("cluster addon chart in %s differs from the chart in %s", filepath.Join(staged, chartDir), filepath.Join(path, "cluster-addon"))
+	}
+
+	return nil
+}
+
+// chartFiles loads the helm chart in dir like helm does and returns the content of its files by name.
+func chartFiles(dir string) (map[string][]byte, error) {
+	chart, err := loader.LoadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart %s: %w", dir, err)
+	}
+
+	files := make(map[string][]byte, len(chart.Raw))
+	for _, file := range chart.Raw {
+		files[filepath.FromSlash(file.Name)] = file.Data
+	}
+
+	return files, nil
+}
+
+// copyDir copies the directory src with its files, directories and symlinks to dst, which must exist.
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(src, path)
+		if err != nil {
+			return fmt.Errorf("failed to relate directory: %w", err)
+		}
+		target := filepath.Join(dst, relativePath)
+
+		switch {
+		case info.IsDir():
+			if err := os.MkdirAll(target, info.Mode().Perm()); err != nil {
+				return fmt.Errorf("failed to create directory: %w", err)
+			}
+			return os.Chmod(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return fmt.Errorf("failed to read link %s: %w", path, err)
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			data, err := os.ReadFile(filepath.Clean(path))
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			return os.WriteFile(target, data, info.Mode().Perm())
+		default:
+			return fmt.Errorf("unsupported file %s", path)
+		}
+	})
+}
+
+func newClusterAddonConfig(addonName string) ClusterAddonConfig {
+	stage := []ClusterAddonStage{{Name: addonName, Action: "apply"}}
+
+	return ClusterAddonConfig{
+		APIVersion:          clusterAddonConfigAPIVersion,
+		ClusterAddonVersion: clusterAddonVersion,
+		AddonStages: map[string][]ClusterAddonStage{
+			"AfterControlPlaneInitialized": stage,
+			"BeforeClusterUpgrade":         stage,
+		},
+	}
+}
+
+func marshalYAML(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterstack
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// copyFixture copies the legacy cluster stack ferrol into a temporary directory, which contains nothing else.
+func copyFixture(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "ferrol")
+	if err := os.Mkdir(path, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := copyDir(filepath.Join("..", "..", "tests", "cluster-stacks", "docker", "ferrol"), path); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func readTree(t *testing.T, path string) map[string]string {
+	t.Helper()
+
+	tree := make(map[string]string)
+	if err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(filepath.Clean(file))
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(path, file)
+		if err != nil {
+			return err
+		}
+		tree[relativePath] = string(data)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	return tree
+}
+
+func assertOnlyEntry(t *testing.T, path string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != filepath.Base(path) {
+		names := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		t.Errorf("expected only %s next to the cluster stack, got %v", filepath.Base(path), names)
+	}
+}
+
+func TestApplyMigration(t *testing.T) {
+	path := copyFixture(t)
+	before := readTree(t, path)
+
+	changes, err := PlanMigration(path, "addon")
+	if err != nil {
+		t.Fatalf("failed to plan migration: %v", err)
+	}
+
+	if err := ApplyMigration(path, changes); err != nil {
+		t.Fatalf("failed to apply migration: %v", err)
+	}
+
+	after := readTree(t, path)
+	if _, ok := after["clusteraddon.yaml"]; !ok {
+		t.Error("clusteraddon.yaml was not created")
+	}
+	if _, ok := after["cluster-addon-values.yaml"]; ok {
+		t.Error("cluster-addon-values.yaml was not deleted")
+	}
+	for name, content := range before {
+		rel, ok := strings.CutPrefix(name, "cluster-addon"+string(filepath.Separator))
+		if !ok {
+			continue
+		}
+		if after[filepath.Join("cluster-addon", "addon", rel)] != content {
+			t.Errorf("%s was not moved to %s", name, filepath.Join("cluster-addon", "addon", rel))
+		}
+	}
+	if err := ValidateLayout(path); err != nil {
+		t.Errorf("migrated cluster stack is invalid: %v", err)
+	}
+	assertOnlyEntry(t, path)
+}
+
+func TestApplyMigrationFailureLeavesClusterStack(t *testing.T) {
+	path := copyFixture(t)
+	before := readTree(t, path)
+
+	changes, err := PlanMigration(path, "addon")
+	if err != nil {
+		t.Fatalf("failed to plan migration: %v", err)
+	}
+	// Deleting the cluster class makes the migrated cluster stack invalid after all other changes are applied.
+	changes = append(changes, MigrationChange{Action: MigrationDelete, Path: filepath.Join("cluster-class", "Chart.yaml")})
+
+	if err := ApplyMigration(path, changes); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	after := readTree(t, path)
+	if len(after) != len(before) {
+		t.Errorf("got %d files after the failed migration, want %d", len(after), len(before))
+	}
+	for name, content := range before {
+		if after[name] != content {
+			t.Errorf("%s was changed by the failed migration", name)
+		}
+	}
+	assertOnlyEntry(t, path)
+}
@@ -17,14 +17,20 @@ limitations under the License.
 package clusterstack
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/SovereignCloudStack/cluster-stack-operator/pkg/version"
 	"github.com/SovereignCloudStack/csctl/pkg/hash"
+	"github.com/SovereignCloudStack/csctl/pkg/logging"
 )
 
 // HandleStableMode returns metadata for the stable mode.
-func HandleStableMode(gitHubReleasePath string, currentReleaseHash, latestReleaseHash hash.ReleaseHash) (*MetaData, error) {
+// The ClusterStack version, which is also the version of the ClusterClass, is always bumped. The component versions
+// are only bumped if their hashes changed. The hash of node-images.yaml is only compared if both release hashes contain it.
+func HandleStableMode(ctx context.Context, gitHubReleasePath string, currentReleaseHash, latestReleaseHash hash.ReleaseHash) (*MetaData, error) {
+	logger := logging.FromContext(ctx)
+
 	metadata, err := ParseMetaData(gitHubReleasePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse metadata: %w", err)
@@ -34,28 +40,31 @@ func HandleStableMode(gitHubReleasePath string, currentReleaseHash, latestReleas
 	if err != nil {
 		return nil, fmt.Errorf("failed to bump cluster stack: %w", err)
 	}
+	if currentReleaseHash.ClusterClassChanged(latestReleaseHash) {
+		logger.Info("ClusterClass changed", "version", metadata.Versions.ClusterStack)
+	}
 
 	if currentReleaseHash.ClusterAddonDir != latestReleaseHash.ClusterAddonDir || currentReleaseHash.ClusterAddonValues != latestReleaseHash.ClusterAddonValues {
 		metadata.Versions.Components.ClusterAddon, err = BumpVersion(metadata.Versions.Components.ClusterAddon)
 		if err != nil {
 			return nil, fmt.Errorf("failed to bump cluster addon: %w", err)
 		}
-		fmt.Printf("Bumped ClusterAddon Version: %s\n", metadata.Versions.Components.ClusterAddon)
+		logger.Info("Bumped ClusterAddon Version", "version", metadata.Versions.Components.ClusterAddon)
 	} else {
-		fmt.Printf("ClusterAddon Version unchanged: %s\n", metadata.Versions.Components.ClusterAddon)
+		logger.Info("ClusterAddon Version unchanged", "version", metadata.Versions.Components.ClusterAddon)
 	}
 
-	if currentReleaseHash.NodeImageDir != latestReleaseHash.NodeImageDir {
+	if currentReleaseHash.NodeImageChanged(latestReleaseHash) {
 		metadata.Versions.Components.NodeImage, err = BumpVersion(metadata.Versions.Components.NodeImage)
 		if err != nil {
 			return nil, fmt.Errorf("failed to bump node image: %w", err)
 		}
-		fmt.Printf("Bumped NodeImage Version: %s\n", metadata.Versions.Components.NodeImage)
+		logger.Info("Bumped NodeImage Version", "version", metadata.Versions.Components.NodeImage)
 	} else {
 		if metadata.Versions.Components.NodeImage == "" {
-			fmt.Println("No NodeImage Version.")
+			logger.Info("No NodeImage Version")
 		} else {
-			fmt.Printf("NodeImage Version unchanged: %s\n", metadata.Versions.Components.NodeImage)
+			logger.Info("NodeImage Version unchanged", "version", metadata.Versions.Components.NodeImage)
 		}
 	}
 
@@ -63,9 +72,27 @@ func HandleStableMode(gitHubReleasePath string, currentReleaseHash, latestReleas
 }
 
 // HandleHashMode handles the hash mode with the cluster stack hash.
-func HandleHashMode(currentRelease hash.ReleaseHash, kubernetesVersion string) *MetaData {
-	clusterStackHash := currentRelease.GetClusterStackHash()
-	clusterStackHash = fmt.Sprintf("v0-sha.%s", clusterStackHash)
+func HandleHashMode(currentRelease hash.ReleaseHash, kubernetesVersion string) (*MetaData, error) {
+	clusterStackHash, err := currentRelease.GetClusterStackHash()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster stack hash: %w", err)
+	}
+
+	return hashModeMetadata(clusterStackHash, kubernetesVersion), nil
+}
+
+// HandleGitHashMode handles the hash mode with the short hash of a git commit instead of the cluster stack hash.
+func HandleGitHashMode(commitHash, kubernetesVersion string) (*MetaData, error) {
+	if commitHash == "" {
+		return nil, fmt.Errorf("git commit hash is empty")
+	}
+
+	return hashModeMetadata(commitHash, kubernetesVersion), nil
+}
+
+// hashModeMetadata returns the metadata of the hash mode, in which all versions are v0-sha.<hash>.
+func hashModeMetadata(shortHash, kubernetesVersion string) *MetaData {
+	clusterStackHash := fmt.Sprintf("v0-sha.%s", shortHash)
 
 	return &MetaData{
 		APIVersion: "metadata.clusterstack.x-k8s.io/v1alpha1",
@@ -0,0 +1,126 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterstack
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SovereignCloudStack/csctl/pkg/hash"
+)
+
+const latestMetadata = `apiVersion: metadata.clusterstack.x-k8s.io/v1alpha1
+versions:
+  clusterStack: v1
+  kubernetes: v1.27.7
+  components:
+    clusterAddon: v1
+    nodeImage: v1
+`
+
+func TestHandleStableMode(t *testing.T) {
+	latest := hash.ReleaseHash{
+		HashVersion:        1,
+		ClusterStack:       "stack",
+		ClusterClassDir:    "class",
+		ClusterAddonDir:    "addon",
+		ClusterAddonValues: "values",
+		NodeImageDir:       "nodeimagedir",
+		NodeImages:         "nodeimages",
+	}
+
+	tests := []struct {
+		name             string
+		change           func(current *hash.ReleaseHash)
+		wantClusterAddon string
+		wantNodeImage    string
+	}{
+		{
+			name:             "nothing changed",
+			change:           func(*hash.ReleaseHash) {},
+			wantClusterAddon: "v1",
+			wantNodeImage:    "v1",
+		},
+		{
+			name:             "only node-images.yaml changed",
+			change:           func(current *hash.ReleaseHash) { current.NodeImages = "othernodeimages" },
+			wantClusterAddon: "v1",
+			wantNodeImage:    "v2",
+		},
+		{
+			name:             "node-images.yaml not hashed",
+			change:           func(current *hash.ReleaseHash) { current.NodeImages = "" },
+			wantClusterAddon: "v1",
+			wantNodeImage:    "v1",
+		},
+		{
+			name:             "node-image directory changed",
+			change:           func(current *hash.ReleaseHash) { current.NodeImageDir = "othernodeimagedir" },
+			wantClusterAddon: "v1",
+			wantNodeImage:    "v2",
+		},
+		{
+			name:             "cluster addon changed",
+			change:           func(current *hash.ReleaseHash) { current.ClusterAddonDir = "otheraddon" },
+			wantClusterAddon: "v2",
+			wantNodeImage:    "v1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "metadata.yaml"), []byte(latestMetadata), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			current := latest
+			tt.change(&current)
+
+			metadata, err := HandleStableMode(context.Background(), dir, current, latest)
+			if err != nil {
+				t.Fatalf("HandleStableMode() failed: %v", err)
+			}
+
+			if metadata.Versions.ClusterStack != "v2" {
+				t.Errorf("cluster stack version = %q, want v2", metadata.Versions.ClusterStack)
+			}
+			if metadata.Versions.Components.ClusterAddon != tt.wantClusterAddon {
+				t.Errorf("cluster addon version = %q, want %q", metadata.Versions.Components.ClusterAddon, tt.wantClusterAddon)
+			}
+			if metadata.Versions.Components.NodeImage != tt.wantNodeImage {
+				t.Errorf("node image version = %q, want %q", metadata.Versions.Components.NodeImage, tt.wantNodeImage)
+			}
+		})
+	}
+}
+
+func TestHandleHashModeShortHash(t *testing.T) {
+	if _, err := HandleHashMode(hash.ReleaseHash{ClusterStack: "abc"}, "v1.27.7"); err == nil {
+		t.Error("expected an error for a 3 character hash")
+	}
+
+	metadata, err := HandleHashMode(hash.ReleaseHash{ClusterStack: "abcdefghijk"}, "v1.27.7")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if metadata.Versions.ClusterStack != "v0-sha.abcdefg" {
+		t.Errorf("got cluster stack version %q, want %q", metadata.Versions.ClusterStack, "v0-sha.abcdefg")
+	}
+}
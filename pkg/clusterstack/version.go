@@ -18,32 +18,93 @@ package clusterstack
 
 import (
 	"fmt"
-	"regexp"
+	"slices"
 	"strconv"
+	"strings"
+
+	"github.com/SovereignCloudStack/cluster-stack-operator/pkg/version"
 )
 
-// BumpVersion bumps the version of the cluster stacks component.
-func BumpVersion(version string) (string, error) {
-	// Define a regular expression to extract the numeric part of the version
-	re := regexp.MustCompile(`(\d+)`)
-	matches := re.FindStringSubmatch(version)
+// BumpLevel defines which part of a version gets bumped. Cluster stack versions have no minor or patch version,
+// only the major version and, in pre-release channels, a numeric suffix.
+type BumpLevel string
+
+const (
+	// BumpMajor bumps the major version, e.g. "v1" to "v2" or "v1-alpha.3" to "v2-alpha.0".
+	BumpMajor = BumpLevel("major")
+	// BumpPrerelease bumps the numeric suffix of a pre-release channel, e.g. "v2-beta.3" to "v2-beta.4".
+	// It fails for versions of the stable channel, which have no numeric suffix.
+	BumpPrerelease = BumpLevel("prerelease")
+)
+
+// VersionPaths are the paths of the versions in metadata.yaml that can be set with SetVersion.
+var VersionPaths = []string{"versions.clusterStack", "versions.components.clusterAddon", "versions.components.nodeImage"}
+
+// SetVersion sets the version at path in metadata, e.g. versions.components.nodeImage.
+func SetVersion(metadata *MetaData, path, v string) error {
+	if !slices.Contains(VersionPaths, path) {
+		return fmt.Errorf("unknown version %q, supported are %s", path, strings.Join(VersionPaths, ", "))
+	}
+
+	if _, err := version.New(v); err != nil {
+		return fmt.Errorf("failed to verify version %q for %s: %w", v, path, err)
+	}
+
+	switch path {
+	case "versions.clusterStack":
+		metadata.Versions.ClusterStack = v
+	case "versions.components.clusterAddon":
+		metadata.Versions.Components.ClusterAddon = v
+	case "versions.components.nodeImage":
+		metadata.Versions.Components.NodeImage = v
+	}
+
+	return nil
+}
 
-	// Check if a numeric part was found
-	if len(matches) < 2 {
-		return "", fmt.Errorf("invalid version format")
+// BumpVersion bumps the release counter of the cluster stacks component.
+// The release counter is the major version in the stable channel, e.g. "v1" to "v2",
+// and the numeric suffix in other channels, e.g. "v1-alpha.0" to "v1-alpha.1".
+func BumpVersion(v string) (string, error) {
+	parsed, err := version.New(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse version %q: %w", v, err)
 	}
 
-	// Extract and convert the numeric part to an integer
-	currentMajor, err := strconv.Atoi(matches[1])
+	level := BumpPrerelease
+	if parsed.Channel == version.ChannelStable {
+		level = BumpMajor
+	}
+
+	return BumpVersionWithLevel(v, level)
+}
+
+// BumpVersionWithLevel bumps the given level of the version of the cluster stacks component.
+func BumpVersionWithLevel(v string, level BumpLevel) (string, error) {
+	parsed, err := version.New(v)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse major version: %w", err)
+		return "", fmt.Errorf("failed to parse version %q: %w", v, err)
 	}
 
-	// Increment the major version
-	newMajor := currentMajor + 1
+	switch level {
+	case BumpMajor:
+		parsed.Major++
+		if parsed.Channel != version.ChannelStable {
+			parsed.Patch = "0"
+		}
+	case BumpPrerelease:
+		if parsed.Channel == version.ChannelStable {
+			return "", fmt.Errorf("version %q of the stable channel has no pre-release version, bump the major version instead", v)
+		}
 
-	// Replace the old major version with the new one
-	newVersion := re.ReplaceAllString(version, strconv.Itoa(newMajor))
+		patch, err := strconv.Atoi(parsed.Patch)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse pre-release number %q: %w", parsed.Patch, err)
+		}
+		parsed.Patch = strconv.Itoa(patch + 1)
+	default:
+		return "", fmt.Errorf("unknown bump level %q, supported are %s and %s", level, BumpMajor, BumpPrerelease)
+	}
 
-	return newVersion, nil
+	return parsed.StringWithDot(), nil
 }
@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterstack
+
+import "testing"
+
+func TestBumpVersionWithLevel(t *testing.T) {
+	tests := []struct {
+		version string
+		level   BumpLevel
+		want    string
+		wantErr bool
+	}{
+		{version: "v1", level: BumpMajor, want: "v2"},
+		{version: "v1", level: BumpPrerelease, wantErr: true},
+		{version: "v1-alpha.0", level: BumpMajor, want: "v2-alpha.0"},
+		{version: "v1-alpha.0", level: BumpPrerelease, want: "v1-alpha.1"},
+		{version: "v2-beta.3", level: BumpMajor, want: "v3-beta.0"},
+		{version: "v2-beta.3", level: BumpPrerelease, want: "v2-beta.4"},
+		{version: "v1", level: BumpLevel("minor"), wantErr: true},
+		{version: "v1-alpha.0", level: BumpLevel("patch"), wantErr: true},
+		{version: "v1", level: BumpLevel("build"), wantErr: true},
+		{version: "1.0.0", level: BumpMajor, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version+"/"+string(tt.level), func(t *testing.T) {
+			got, err := BumpVersionWithLevel(tt.version, tt.level)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBumpVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{version: "v1", want: "v2"},
+		{version: "v1-alpha.0", want: "v1-alpha.1"},
+		{version: "v2-beta.3", want: "v2-beta.4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			got, err := BumpVersion(tt.version)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
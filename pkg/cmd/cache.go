@@ -0,0 +1,61 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/SovereignCloudStack/csctl/pkg/cache"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manages the cache of downloaded releases",
+	Long: `csctl caches the latest releases that are downloaded to compute the next version.
+	The cache is in $XDG_CACHE_HOME/csctl.`,
+}
+
+var cacheCleanCmd = &cobra.Command{
+	Use:          "clean",
+	Short:        "Removes all cached releases",
+	Example:      `csctl cache clean`,
+	RunE:         cacheCleanAction,
+	SilenceUsage: true,
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheCleanCmd)
+}
+
+func cacheCleanAction(_ *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("cache clean does not accept any arguments")
+	}
+
+	releaseCache, err := cache.New()
+	if err != nil {
+		return fmt.Errorf("failed to open cache: %w", err)
+	}
+
+	if err := releaseCache.Clean(); err != nil {
+		return fmt.Errorf("failed to clean cache: %w", err)
+	}
+
+	fmt.Printf("Removed cache %s\n", releaseCache.Dir())
+	return nil
+}
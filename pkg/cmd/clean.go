@@ -0,0 +1,166 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// tmpDirMarker is written by csctl create into its temporary directory. csctl clean only removes temporary
+// directories that contain it, so that directories of other programs named csctl-* are left alone.
+const tmpDirMarker = ".csctl-create"
+
+var (
+	cleanOutputDirectory string
+	cleanTmpDir          string
+	cleanOlderThan       time.Duration
+)
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Removes the output directory and leftover temporary directories of csctl create",
+	Long: `It removes the output directory of csctl create, which contains the created releases, and the
+	temporary directories csctl-* that were kept with --keep-tmp or left behind by a crash.
+	Temporary directories are only removed if they were created by csctl create more than --older-than ago,
+	so that the directories of running builds are kept. Nothing outside of them is removed. Directories that look like a cluster stack or a git repository,
+	the current directory and its parents are never removed.`,
+	Example:      `csctl clean -o ./.release`,
+	RunE:         cleanAction,
+	SilenceUsage: true,
+}
+
+func init() {
+	cleanCmd.Flags().StringVarP(&cleanOutputDirectory, "output", "o", "./.release", "The output directory of csctl create that is removed")
+	cleanCmd.Flags().StringVar(&cleanTmpDir, "tmp-dir", "", "Directory in which the temporary directories of csctl create were created. Defaults to the temporary directory of the system")
+	cleanCmd.Flags().DurationVar(&cleanOlderThan, "older-than", 24*time.Hour, "Only remove temporary directories of csctl create that were created longer ago, so that running builds are not affected. 0 removes all of them")
+}
+
+func cleanAction(_ *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("clean does not accept any arguments")
+	}
+
+	removed, err := removeOutputDirectory(cleanOutputDirectory)
+	if err != nil {
+		return err
+	}
+	if removed {
+		fmt.Printf("Removed %s\n", cleanOutputDirectory)
+	}
+
+	tmpDirs, err := removeTmpDirectories(cleanTmpDir, time.Now().Add(-cleanOlderThan))
+	for _, dir := range tmpDirs {
+		fmt.Printf("Removed %s\n", dir)
+	}
+
+	return err
+}
+
+// removeOutputDirectory removes the output directory of csctl create. It refuses to remove directories that are
+// likely not an output directory, like the current directory or a cluster stack. It returns false if the directory
+// does not exist.
+func removeOutputDirectory(dir string) (bool, error) {
+	info, err := os.Lstat(dir)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to stat output directory %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return false, fmt.Errorf("refusing to remove output directory %s: it is not a directory", dir)
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false, fmt.Errorf("filepath.Abs(%q) failed: %w", dir, err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return false, fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	home, _ := os.UserHomeDir()
+	if absDir == filepath.Dir(absDir) || absDir == home || isParentOrSame(absDir, wd) {
+		return false, fmt.Errorf("refusing to remove output directory %s: it is the root, the home or the current directory or a parent of it", dir)
+	}
+
+	for _, name := range []string{"csctl.yaml", ".git"} {
+		if _, err := os.Stat(filepath.Join(absDir, name)); err == nil {
+			return false, fmt.Errorf("refusing to remove output directory %s: it contains %s", dir, name)
+		}
+	}
+
+	if err := os.RemoveAll(absDir); err != nil {
+		return false, fmt.Errorf("failed to remove output directory %s: %w", dir, err)
+	}
+
+	return true, nil
+}
+
+// removeTmpDirectories removes the temporary directories csctl-* of csctl create in tmpDir that were created before
+// the given time and returns them. Directories without tmpDirMarker were not created by csctl create and are kept.
+// tmpDir itself is never removed.
+func removeTmpDirectories(tmpDir string, before time.Time) ([]string, error) {
+	if tmpDir == "" {
+		tmpDir = os.TempDir()
+	}
+
+	matches, err := filepath.Glob(filepath.Join(tmpDir, "csctl-*"))
+	if err != nil {
+		return nil, fmt.Errorf("glob for temporary directories in %s failed: %w", tmpDir, err)
+	}
+
+	var removed []string
+	for _, match := range matches {
+		// Symlinks are skipped, so that nothing outside of tmpDir is removed.
+		info, err := os.Lstat(match)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+
+		// The marker is written when the directory is created, so its modification time is the start of the build.
+		marker, err := os.Lstat(filepath.Join(match, tmpDirMarker))
+		if err != nil || !marker.Mode().IsRegular() || !marker.ModTime().Before(before) {
+			continue
+		}
+
+		if err := os.RemoveAll(match); err != nil {
+			return removed, fmt.Errorf("failed to remove temporary directory %s: %w", match, err)
+		}
+		removed = append(removed, match)
+	}
+
+	return removed, nil
+}
+
+// isParentOrSame returns true if dir is path or one of its parents.
+func isParentOrSame(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRemoveTmpDirectories(t *testing.T) {
+	tmpDir := t.TempDir()
+	now := time.Now()
+
+	createDir := func(name string, marker bool, created time.Time) string {
+		dir := filepath.Join(tmpDir, name)
+		if err := os.Mkdir(dir, 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if marker {
+			markerPath := filepath.Join(dir, tmpDirMarker)
+			if err := os.WriteFile(markerPath, nil, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(markerPath, created, created); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return dir
+	}
+
+	old := createDir("csctl-old", true, now.Add(-48*time.Hour))
+	running := createDir("csctl-running", true, now.Add(-time.Minute))
+	foreign := createDir("csctl-foreign", false, time.Time{})
+	other := createDir("other", true, now.Add(-48*time.Hour))
+	if err := os.Symlink(old, filepath.Join(tmpDir, "csctl-link")); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := removeTmpDirectories(tmpDir, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !slices.Equal(removed, []string{old}) {
+		t.Errorf("removed %v, want %v", removed, []string{old})
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("%s was not removed", old)
+	}
+	for _, dir := range []string{running, foreign, other} {
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("%s was removed: %v", dir, err)
+		}
+	}
+}
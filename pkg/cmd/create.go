@@ -19,23 +19,34 @@ package cmd
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/SovereignCloudStack/csctl/pkg/assetsclient"
 	"github.com/SovereignCloudStack/csctl/pkg/assetsclient/github"
+	"github.com/SovereignCloudStack/csctl/pkg/assetsclient/gitlab"
 	"github.com/SovereignCloudStack/csctl/pkg/assetsclient/oci"
 	"github.com/SovereignCloudStack/csctl/pkg/clusterstack"
 	"github.com/SovereignCloudStack/csctl/pkg/hash"
+	"github.com/SovereignCloudStack/csctl/pkg/logging"
+	"github.com/SovereignCloudStack/csctl/pkg/mediatype"
 	"github.com/SovereignCloudStack/csctl/pkg/providerplugin"
+	"github.com/SovereignCloudStack/csctl/pkg/release"
 	"github.com/SovereignCloudStack/csctl/pkg/template"
+	imagev1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
 )
 
 const (
 	stableMode = "stable"
+	alphaMode  = "alpha"
+	betaMode   = "beta"
 	hashMode   = "hash"
 	customMode = "custom"
 )
@@ -46,12 +57,21 @@ var (
 	the cluster stack release in the current directory named "release/".
 	Supported modes are - stable, alpha, beta, hash
 
-	note - Hash mode takes the last hash of the git commit.`
+	note - Hash mode takes the hash of the content of the cluster stack.
+	With --git-hash, it takes the short hash of the HEAD commit of the git
+	repository that contains the cluster stack instead.
+
+	If nothing changed since the latest release, csctl exits with code 2.
+	Use --force to create the release anyway.`
 	example = `csctl create tests/cluster-stacks/docker/ferrol -m hash (for hash mode)
 
 csctl create tests/cluster-stacks/docker/ferrol -m hash github-release/ (for stable mode)
 
-csctl create --publish --remote oci tests/cluster-stacks/docker/ferrol (publish to OCI repository)`
+csctl create --publish --remote oci tests/cluster-stacks/docker/ferrol (publish to OCI repository)
+
+csctl create --publish --remote github tests/cluster-stacks/docker/ferrol (publish as GitHub release)
+
+csctl create --publish --remote gitlab tests/cluster-stacks/docker/ferrol (publish to the GitLab package registry)`
 )
 
 var (
@@ -63,8 +83,61 @@ var (
 	nodeImageVersion    string
 	remote              string
 	publish             bool
+	updateLatest        bool
+	latestAlias         string
+	force               bool
+	plugin              string
+	pluginTimeout       time.Duration
+	compression         string
+	strict              bool
+	tmpDir              string
+	keepTmp             bool
+	strictTemplates     bool
+	valuesFile          string
+	updateDependencies  bool
+	skipLint            bool
+	strictLint          bool
+	helmSign            bool
+	helmKey             string
+	helmKeyring         string
+	helmPassphraseFile  string
+	annotationFlags     []string
+	outputFormat        string
+	latestReleaseDir    string
+	gitHash             bool
+	requireClean        bool
+	recursive           bool
+	kubernetesVersions  []string
+	dryRun              bool
+	yes                 bool
+	artifactType        string
+	mediaTypesFile      string
+	clean               bool
+	hashNodeImages      bool
+	setFlags            []string
 )
 
+// createResult is the json representation of the result of the create command.
+type createResult struct {
+	ClusterStackPath string                 `json:"clusterStackPath,omitempty"`
+	ReleaseName      string                 `json:"releaseName,omitempty"`
+	OutputDir        string                 `json:"outputDir,omitempty"`
+	Mode             string                 `json:"mode,omitempty"`
+	Versions         *clusterstack.Versions `json:"versions,omitempty"`
+	Reference        string                 `json:"reference,omitempty"`
+	Digest           string                 `json:"digest,omitempty"`
+	ArtifactType     string                 `json:"artifactType,omitempty"`
+	DryRun           bool                   `json:"dryRun,omitempty"`
+	Assets           []releaseAssetInfo     `json:"assets,omitempty"`
+	Error            string                 `json:"error,omitempty"`
+}
+
+// releaseAssetInfo describes a file of the release.
+type releaseAssetInfo struct {
+	Name      string `json:"name"`
+	MediaType string `json:"mediaType,omitempty"`
+}
+
 // CreateOptions contains config for creating a release.
 type CreateOptions struct {
 	newClusterStackConvention bool
@@ -75,7 +148,24 @@ type CreateOptions struct {
 	CurrentReleaseHash        hash.ReleaseHash
 	LatestReleaseHash         hash.ReleaseHash
 	NodeImageRegistry         string
+	Compression               template.Compression
+	TemplateValues            map[string]interface{}
+	HelmOptions               template.HelmOptions
+	Annotations               map[string]string
 	releaseName               string
+	tmpDir                    string
+	pushedReference           string
+	pushedDigest              string
+	// assets are the files of the release. They are only set for a dry run.
+	assets []releaseAssetInfo
+	// latestReleasePath is the directory of the latest release the versions were computed from in stable, alpha
+	// and beta mode. It is empty if there is no latest release.
+	latestReleasePath string
+	// comparedReleaseHash is the hash of the latest release the versions were computed from. It is empty if the
+	// hashes cannot be compared.
+	comparedReleaseHash hash.ReleaseHash
+	// versionOverrides are the versions of --set.
+	versionOverrides map[string]string
 }
 
 // createCmd represents the create command.
@@ -91,23 +181,76 @@ var createCmd = &cobra.Command{
 func init() {
 	createCmd.Flags().StringVarP(&mode, "mode", "m", "stable", "It defines the mode of the cluster stack manager")
 	createCmd.Flags().StringVarP(&outputDirectory, "output", "o", "./.release", "It defines the output directory in which the release artifacts will be generated")
-	createCmd.Flags().StringVarP(&nodeImageRegistry, "node-image-registry", "r", "", "It defines the node image registry. For example oci://ghcr.io/foo/bar/node-images/staging/")
+	createCmd.Flags().StringVarP(&nodeImageRegistry, "node-image-registry", "r", "", "It defines the node image registry in the format oci://<host>/<path> or s3://<bucket>/<path>. For example oci://ghcr.io/foo/bar/node-images/staging/")
 	createCmd.Flags().StringVar(&clusterStackVersion, "cluster-stack-version", "", "It is used to specify the semver version for the cluster stack in the custom mode")
 	createCmd.Flags().StringVar(&clusterAddonVersion, "cluster-addon-version", "", "It is used to specify the semver version for the cluster addon in the custom mode")
 	createCmd.Flags().StringVar(&nodeImageVersion, "node-image-version", "", "It is used to specify the semver version for the node images in the custom mode")
-	createCmd.Flags().StringVar(&remote, "remote", "github", "Which remote repository to use and thus which credentials are required. Currently supported are 'github' and 'oci'.")
-	createCmd.Flags().BoolVar(&publish, "publish", false, "Publish release after creation is done. This is only implemented for OCI currently.")
+	createCmd.Flags().StringVar(&remote, "remote", "github", "Which remote repository to use and thus which credentials are required. Currently supported are 'github', 'oci' and 'gitlab'.")
+	createCmd.Flags().BoolVar(&publish, "publish", false, "Publish release after creation is done. The release is published to the repository defined by --remote.")
+	createCmd.Flags().StringVar(&compression, "compression", template.CompressionGzip, "Compression of the generated tar packages in the format <algorithm>[:<level>]. Supported algorithms are 'gzip' and 'zstd', e.g. gzip:9 or zstd")
+	createCmd.Flags().StringVar(&plugin, "plugin", "", "Path of the provider plugin to use instead of searching csctl-<provider> in the current directory, $CSCTL_PLUGIN_PATH and $PATH")
+	createCmd.Flags().DurationVar(&pluginTimeout, "plugin-timeout", 0, "Maximum duration of the provider plugin call, e.g. 2h. Zero means no timeout")
+	createCmd.Flags().StringVar(&tmpDir, "tmp-dir", "", "Directory in which the temporary working directory is created. Defaults to the temporary directory of the system")
+	createCmd.Flags().BoolVar(&helmSign, "helm-sign", false, "Sign the helm packages and create provenance files, like helm package --sign. Requires --helm-key")
+	createCmd.Flags().StringVar(&helmKey, "helm-key", "", "Name of the GPG key used by --helm-sign")
+	createCmd.Flags().StringVar(&helmKeyring, "helm-keyring", defaultHelmKeyring(), "Keyring that contains the secret GPG key used by --helm-sign")
+	createCmd.Flags().StringVar(&helmPassphraseFile, "helm-passphrase-file", "", "File that contains the passphrase of the GPG key used by --helm-sign. Use - to read it from stdin. If not set, the passphrase is prompted")
+	createCmd.Flags().BoolVar(&skipLint, "skip-lint", false, "Do not lint the helm charts before packaging them")
+	createCmd.Flags().BoolVar(&strictLint, "strict-lint", false, "Fail on lint warnings of the helm charts, not only on lint errors")
+	createCmd.Flags().BoolVar(&updateDependencies, "update-dependencies", false, "Download the dependencies of the helm charts before packaging them, like helm dependency build. Chart.lock is respected")
+	createCmd.Flags().StringVar(&valuesFile, "values", "", "YAML file with additional values for templating, e.g. myKey: foo is used as << .myKey >>. Built-in values take precedence")
+	createCmd.Flags().BoolVar(&strictTemplates, "strict-templates", true, "Fail if a templated file contains a << placeholder >> without value. If false, such placeholders are left in the output with a warning")
+	createCmd.Flags().BoolVar(&clean, "clean", false, "Remove the output directory with the releases of earlier runs before creating the release")
+	createCmd.Flags().BoolVar(&hashNodeImages, "hash-node-images", false, "Write the hash of node-images.yaml created by the provider plugin to hashes.json. In stable, alpha and beta mode, the NodeImage version is bumped if it differs from the hash of the latest release. node-images.yaml may contain environment specific URLs")
+	createCmd.Flags().BoolVar(&keepTmp, "keep-tmp", false, "Keep the temporary directory with the templated cluster stack for debugging")
+	createCmd.Flags().BoolVar(&strict, "strict", false, "Abort publishing if the release directory contains unknown files instead of skipping them with a warning")
+	createCmd.Flags().StringVar(&artifactType, "artifact-type", mediatype.ArtifactType, "Artifact type of the published OCI manifest. It must be a media type like <type>/<subtype>")
+	createCmd.Flags().StringVar(&mediaTypesFile, "media-types", "", "YAML file that overrides the media types of the release files, e.g. metadata: application/yaml. Files that are not listed keep their default media type")
+	createCmd.Flags().StringArrayVar(&annotationFlags, "annotation", nil, "Additional annotation of the published OCI manifest in the format key=value, e.g. org.example.git-sha=abc123. Can be repeated. The reserved keys hash and kubernetesVersion can only be overwritten with --force")
+	createCmd.Flags().StringVar(&outputFormat, "output-format", "", "Format of the result. One of '' or 'json'. With 'json', the release name, output directory, versions and the pushed digest are printed as json, errors as well")
+	createCmd.Flags().StringVar(&latestReleaseDir, "latest-release-dir", "", "Local directory of the latest release, which is used instead of the remote repository in stable mode. It must contain metadata.yaml and hashes.json. No network calls are made")
+	createCmd.Flags().BoolVar(&gitHash, "git-hash", false, "In hash mode, use the short hash of the HEAD commit of the git repository that contains the cluster stack instead of the hash of its content. Falls back to the content hash if the cluster stack is not in a git repository")
+	createCmd.Flags().BoolVar(&requireClean, "require-clean", false, "In hash mode, fail if the cluster stack has uncommitted or untracked changes in its git repository instead of printing a warning")
+	createCmd.Flags().StringArrayVar(&kubernetesVersions, "kubernetes-version", nil, "Kubernetes version of the release in the format v<major>.<minor>.<patch>, which overrides the versions of csctl.yaml. Can be repeated to create one release per version")
+	createCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the versions, the release name and the assets of the release without writing to the output directory, calling the provider plugin or publishing")
+	createCmd.Flags().BoolVarP(&yes, "yes", "y", false, "Publish without asking for confirmation. Required with --publish if stdin is not a terminal")
+	createCmd.Flags().BoolVar(&recursive, "recursive", false, "Create a release for every cluster stack below the given directory, i.e. every directory that contains a csctl.yaml. Failures are reported at the end")
+	createCmd.Flags().StringArrayVar(&setFlags, "set", nil, "Override a computed version of the release in the format path=version. Supported paths are versions.clusterStack, versions.components.clusterAddon and versions.components.nodeImage, e.g. versions.components.nodeImage=v2. Can be repeated")
+	createCmd.Flags().BoolVar(&force, "force", false, "Create the release even if nothing changed since the latest release")
+	createCmd.Flags().BoolVar(&updateLatest, "update-latest", false, "Tag the published release additionally with a floating latest tag. This is only supported in stable mode for remote OCI.")
+	createCmd.Flags().StringVar(&latestAlias, "latest-alias", "", "The floating tag used by --update-latest. Defaults to <provider>-<cluster-stack-name>-<kubernetes-version>-latest, e.g. docker-ferrol-1-27-latest")
 }
 
 // GetCreateOptions create a Create Option for create command.
-func GetCreateOptions(ctx context.Context, clusterStackPath string) (*CreateOptions, error) {
-	createOption := &CreateOptions{}
+// If csctl.yaml contains several Kubernetes versions, kubernetesVersion selects the one of the release.
+// Otherwise, kubernetesVersion is empty. Temporary files are written to tmpDir, which is owned by the caller.
+func GetCreateOptions(ctx context.Context, clusterStackPath, kubernetesVersion, tmpDir string) (*CreateOptions, error) {
+	createOption := &CreateOptions{tmpDir: tmpDir}
+
+	if err := validateNodeImageRegistry(nodeImageRegistry); err != nil {
+		return nil, fmt.Errorf("invalid --node-image-registry: %w", err)
+	}
+
+	var err error
+
+	createOption.versionOverrides, err = parseVersionOverrides(setFlags)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --set: %w", err)
+	}
 
 	// ClusterAddon config
 	config, err := clusterstack.GetCsctlConfig(clusterStackPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get config: %w", err)
 	}
+	if kubernetesVersion != "" {
+		config, err = config.WithKubernetesVersion(kubernetesVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to select kubernetes version: %w", err)
+		}
+	} else if len(config.Config.KubernetesVersions) > 0 {
+		return nil, fmt.Errorf("csctl.yaml contains several kubernetes versions, please select one of them")
+	}
 	createOption.ClusterStackPath = clusterStackPath
 	createOption.Config = config
 
@@ -121,31 +264,56 @@ func GetCreateOptions(ctx context.Context, clusterStackPath string) (*CreateOpti
 		createOption.newClusterStackConvention = true
 	}
 
-	_, _, err = providerplugin.GetProviderExecutable(config)
+	_, _, err = providerplugin.GetProviderExecutable(config, plugin)
 	if err != nil {
 		return createOption, fmt.Errorf("providerplugin.GetProviderExecutable(&config) failed: %w", err)
 	}
 
-	currentHash, err := hash.GetHash(clusterStackPath)
+	currentHash, err := hash.GetHash(ctx, clusterStackPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get hash: %w", err)
 	}
 	createOption.CurrentReleaseHash = currentHash
 
+	// The releases of the remote repository and the latest of them, if the version was computed from them.
+	var (
+		remoteReleases    []string
+		latestRepoRelease string
+		checkRemote       bool
+	)
+
 	switch mode {
 	case hashMode:
-		createOption.Metadata = clusterstack.HandleHashMode(createOption.CurrentReleaseHash, config.Config.KubernetesVersion)
-	case stableMode:
+		if err := checkCleanWorktree(ctx, clusterStackPath); err != nil {
+			return nil, err
+		}
+
+		createOption.Metadata, err = handleHashMode(ctx, clusterStackPath, createOption.CurrentReleaseHash, config.Config.KubernetesVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to handle hash mode: %w", err)
+		}
+	case stableMode, alphaMode, betaMode:
 		createOption.Metadata = &clusterstack.MetaData{}
 
+		if latestReleaseDir != "" {
+			logging.FromContext(ctx).Info("Using local latest release", "path", latestReleaseDir)
+			if err := createOption.useLatestRelease(ctx, latestReleaseDir); err != nil {
+				return nil, fmt.Errorf("failed to use latest release %s: %w", latestReleaseDir, err)
+			}
+			break
+		}
+
 		var remoteFactory assetsclient.Factory
 
-		// using switch here in case more will be added in the future (aws?)
 		switch remote {
 		case "github":
-			remoteFactory = github.NewFactory()
+			remoteFactory = github.NewFactoryForAssets(clientOptions.Options, releaseAssetNames...)
 		case "oci":
-			remoteFactory = oci.NewFactory()
+			remoteFactory = oci.NewFactory(clientOptions)
+		case "gitlab":
+			remoteFactory = gitlab.NewFactoryForAssets(clientOptions.Options, releaseAssetNames...)
+		default:
+			return nil, fmt.Errorf("remote %q is not supported please choose from - github, oci or gitlab", remote)
 		}
 
 		ac, err := remoteFactory.NewClient(ctx)
@@ -153,35 +321,62 @@ func GetCreateOptions(ctx context.Context, clusterStackPath string) (*CreateOpti
 			return nil, fmt.Errorf("failed to create new asset client: %w", err)
 		}
 
-		latestRepoRelease, err := getLatestReleaseFromRemoteRepository(ctx, mode, config, ac)
+		setStage(fmt.Sprintf("listing the releases of the %s remote", remote))
+		remoteReleases, err = ac.ListRelease(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list releases on remote repository: %w", err)
+		}
+		checkRemote = true
+
+		latestRepoRelease, err = getLatestRelease(ctx, remoteReleases, mode, config)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get latest release form remote repository: %w", err)
 		}
-		fmt.Printf("latest release found: %q\n", latestRepoRelease)
+		logging.FromContext(ctx).Info("Latest release found", "release", latestRepoRelease)
+
+		// Releases of the alpha and beta channel must not be lower than the latest stable release.
+		var latestStableMajor int
+		if mode != stableMode {
+			latestStableRelease, err := getLatestRelease(ctx, remoteReleases, stableMode, config)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get latest stable release form remote repository: %w", err)
+			}
+
+			latestStableMajor, err = getMajorVersion(latestStableRelease)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get major version of latest stable release: %w", err)
+			}
+		}
 
 		if latestRepoRelease == "" {
+			initialVersion := getInitialVersion(mode, latestStableMajor)
+
 			createOption.Metadata.APIVersion = "metadata.clusterstack.x-k8s.io/v1alpha1"
 			createOption.Metadata.Versions.Kubernetes = config.Config.KubernetesVersion
-			createOption.Metadata.Versions.ClusterStack = "v1"
-			createOption.Metadata.Versions.Components.ClusterAddon = "v1"
-			createOption.Metadata.Versions.Components.NodeImage = "v1"
+			createOption.Metadata.Versions.ClusterStack = initialVersion
+			createOption.Metadata.Versions.Components.ClusterAddon = initialVersion
+			createOption.Metadata.Versions.Components.NodeImage = initialVersion
 		} else {
-			if err := downloadReleaseAssets(ctx, latestRepoRelease, "./.tmp/release/", ac); err != nil {
+			releaseDir := filepath.Join(tmpDir, "release")
+			setStage(fmt.Sprintf("downloading the latest release %s", latestRepoRelease))
+			if err := downloadReleaseAssets(ctx, latestRepoRelease, releaseDir, ac); err != nil {
 				return nil, fmt.Errorf("failed to download release asset: %w", err)
 			}
 
-			createOption.LatestReleaseHash, err = hash.ParseReleaseHash("./.tmp/release/hashes.json")
-			if err != nil {
-				return nil, fmt.Errorf("failed to read hash from the github: %w", err)
+			if err := createOption.useLatestRelease(ctx, releaseDir); err != nil {
+				return nil, fmt.Errorf("failed to use release %s: %w", latestRepoRelease, err)
 			}
+		}
 
-			createOption.Metadata, err = clusterstack.HandleStableMode("./.tmp/release/", createOption.CurrentReleaseHash, createOption.LatestReleaseHash)
+		if mode != stableMode {
+			clusterStackMajor, err := getMajorVersion(createOption.Metadata.Versions.ClusterStack)
 			if err != nil {
-				return nil, fmt.Errorf("failed to handle stable mode: %w", err)
+				return nil, fmt.Errorf("failed to get major version of cluster stack version: %w", err)
 			}
 
-			// update the metadata kubernetes version with the csctl.yaml config
-			createOption.Metadata.Versions.Kubernetes = config.Config.KubernetesVersion
+			if clusterStackMajor < latestStableMajor {
+				return nil, fmt.Errorf("version %s of the %s channel would be lower than the latest stable release v%d", createOption.Metadata.Versions.ClusterStack, mode, latestStableMajor)
+			}
 		}
 	case customMode:
 		if clusterStackVersion == "" {
@@ -200,271 +395,684 @@ func GetCreateOptions(ctx context.Context, clusterStackPath string) (*CreateOpti
 		}
 	}
 
-	releaseDirName, err := clusterstack.GetClusterStackReleaseDirectoryName(createOption.Metadata, createOption.Config)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get cluster stack release directory name: %w", err)
+	if err := createOption.applyVersionOverrides(ctx); err != nil {
+		return nil, err
 	}
 
+	// The release is named like its directory.
 	createOption.releaseName, err = clusterstack.GetClusterStackReleaseDirectoryName(createOption.Metadata, createOption.Config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get cluster stack release name: %w", err)
 	}
 
+	if checkRemote {
+		if err := checkNewRelease(remoteReleases, latestRepoRelease, createOption.releaseName); err != nil {
+			if !force {
+				return nil, fmt.Errorf("%w. Use --force to create it anyway", err)
+			}
+			logging.FromContext(ctx).Warn("Creating release anyway because of --force", "reason", err.Error())
+		}
+	}
+
+	// Fail before building if the release cannot be published anyway. pushReleaseAssets checks again before pushing.
+	if publish && remote == "oci" {
+		setStage(fmt.Sprintf("checking if release %s exists", createOption.releaseName))
+		client, err := oci.NewClient(clientOptions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create new oci client: %w", err)
+		}
+		found, err := client.FoundRelease(ctx, createOption.releaseName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check if release %s exists: %w", createOption.releaseName, err)
+		}
+		if found {
+			return nil, fmt.Errorf("release %s already exists in %s", createOption.releaseName, client.Repository.Reference)
+		}
+	}
+
 	// Release directory name `release/docker-ferrol-1-27-v1`
-	createOption.ClusterStackReleaseDir = filepath.Join(outputDirectory, releaseDirName)
+	createOption.ClusterStackReleaseDir = filepath.Join(outputDirectory, createOption.releaseName)
 
 	createOption.NodeImageRegistry = nodeImageRegistry
 
 	return createOption, nil
 }
 
+// useLatestRelease computes the versions of the new release from the latest release in releaseDir.
+// If the hashes of the latest release were computed with another hash version, they cannot be compared and
+// all components are bumped with --force.
+func (c *CreateOptions) useLatestRelease(ctx context.Context, releaseDir string) error {
+	var err error
+	c.LatestReleaseHash, err = hash.ParseReleaseHash(filepath.Join(releaseDir, "hashes.json"))
+	if err != nil {
+		return fmt.Errorf("failed to read hash of the latest release: %w", err)
+	}
+
+	if err := c.LatestReleaseHash.Validate(); err != nil {
+		return fmt.Errorf("hashes.json is invalid: %w", err)
+	}
+
+	latestReleaseHash := c.LatestReleaseHash
+	if err := c.CurrentReleaseHash.CheckVersion(c.LatestReleaseHash); err != nil {
+		if !force {
+			return fmt.Errorf("%w. The hashes of the latest release cannot be compared with the current ones. "+
+				"Use --force to create the release anyway, which bumps the versions of all components", err)
+		}
+		logging.FromContext(ctx).Warn("The hashes of the latest release were computed with another hash version. All components are treated as changed",
+			"latestVersion", c.LatestReleaseHash.Version(), "currentVersion", c.CurrentReleaseHash.Version())
+		latestReleaseHash = hash.ReleaseHash{HashVersion: c.LatestReleaseHash.HashVersion}
+	}
+
+	c.latestReleasePath = releaseDir
+	c.comparedReleaseHash = latestReleaseHash
+
+	return c.handleStableMode(ctx)
+}
+
+// handleStableMode computes the versions of the new release from the latest release and the current release hash.
+func (c *CreateOptions) handleStableMode(ctx context.Context) error {
+	var err error
+	c.Metadata, err = clusterstack.HandleStableMode(ctx, c.latestReleasePath, c.CurrentReleaseHash, c.comparedReleaseHash)
+	if err != nil {
+		return fmt.Errorf("failed to handle %s mode: %w", mode, err)
+	}
+
+	// update the metadata kubernetes version with the csctl.yaml config
+	c.Metadata.Versions.Kubernetes = c.Config.Config.KubernetesVersion
+
+	return nil
+}
+
+// applyVersionOverrides replaces the computed versions with the versions of --set, e.g. to recover from a wrong
+// automatic bump.
+func (c *CreateOptions) applyVersionOverrides(ctx context.Context) error {
+	for _, path := range clusterstack.VersionPaths {
+		if value, ok := c.versionOverrides[path]; ok {
+			if err := clusterstack.SetVersion(c.Metadata, path, value); err != nil {
+				return fmt.Errorf("failed to set version: %w", err)
+			}
+			logging.FromContext(ctx).Info("Overriding version with --set", "path", path, "version", value)
+		}
+	}
+
+	return nil
+}
+
 func createAction(cmd *cobra.Command, args []string) error {
-	defer cleanTmpDirectory()
+	if outputFormat != "" && outputFormat != "json" {
+		return fmt.Errorf("output format %q is not supported please choose from - json", outputFormat)
+	}
 
 	if len(args) != 1 {
 		return fmt.Errorf("please provide a valid command, create only accept one argument to path to the cluster stacks")
 	}
-	clusterStackPath := args[0]
 
-	if mode != stableMode && mode != hashMode && mode != customMode {
-		return fmt.Errorf("mode %q is not supported please choose from - stable, hash or custom", mode)
+	// Temporary directories are not removed, as they might belong to other running invocations of csctl.
+	if clean && !dryRun {
+		if _, err := removeOutputDirectory(outputDirectory); err != nil {
+			return err
+		}
 	}
 
-	createOpts, err := GetCreateOptions(cmd.Context(), clusterStackPath)
-	if err != nil {
-		return fmt.Errorf("failed to create create options: %w", err)
+	if mediaTypesFile != "" {
+		mapping, err := mediatype.ReadMapping(mediaTypesFile)
+		if err != nil {
+			return err
+		}
+		mediaTypes = mapping
 	}
 
-	// Validate if there any change or not
-	if err := createOpts.validateHash(); err != nil {
-		return fmt.Errorf("failed to validate with latest release hash: %w", err)
+	// Fail before building instead of blocking on a confirmation nobody can answer.
+	if publish && !dryRun && !yes && !stdinIsTerminal() {
+		return fmt.Errorf("--publish requires --yes if stdin is not a terminal")
 	}
 
-	if err := createOpts.generateRelease(cmd.Context()); err != nil {
-		return fmt.Errorf("failed to generate release: %w", err)
+	if recursive {
+		return createRecursive(cmd, args[0])
 	}
-	fmt.Printf("Created %s\n", createOpts.ClusterStackReleaseDir)
 
-	return nil
+	targets := getCreateTargets(args[0])
+	if len(targets) > 1 {
+		return createMany(cmd, targets)
+	}
+
+	createOpts, err := runCreate(cmd, targets[0])
+
+	if outputFormat == "json" {
+		if err := printCreateResult(createOpts, err); err != nil {
+			return err
+		}
+	} else if err == nil {
+		printCreated(createOpts)
+	}
+
+	return err
 }
 
-// validateHash returns if some hash changes or not.
-func (c *CreateOptions) validateHash() error {
-	if c.CurrentReleaseHash.ClusterAddonDir == c.LatestReleaseHash.ClusterAddonDir &&
-		c.CurrentReleaseHash.ClusterAddonValues == c.LatestReleaseHash.ClusterAddonValues &&
-		c.CurrentReleaseHash.NodeImageDir == c.LatestReleaseHash.NodeImageDir {
-		return fmt.Errorf("no change in the cluster stack")
+// printCreateResult prints the result of the create command as json. If createErr is not nil, only the error is printed.
+func printCreateResult(createOpts *CreateOptions, createErr error) error {
+	data, err := json.MarshalIndent(newCreateResult(createOpts, createErr), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %w", err)
 	}
+	fmt.Println(string(data))
 
 	return nil
 }
 
-func (c *CreateOptions) generateRelease(ctx context.Context) error {
-	if err := os.MkdirAll(c.ClusterStackReleaseDir, os.ModePerm); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+// newCreateResult returns the result of the create command. If createErr is not nil, only the error is set.
+func newCreateResult(createOpts *CreateOptions, createErr error) createResult {
+	if createErr != nil {
+		return createResult{Error: logging.Redact(createErr.Error())}
 	}
-	fmt.Printf("Creating output in %s\n", c.ClusterStackReleaseDir)
-	// Write the current hash
-	hashJSONData, err := json.MarshalIndent(c.CurrentReleaseHash, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal hash json: %w", err)
+
+	result := createResult{
+		ReleaseName: createOpts.releaseName,
+		OutputDir:   createOpts.ClusterStackReleaseDir,
+		Mode:        mode,
+		Versions:    &createOpts.Metadata.Versions,
+		Reference:   createOpts.pushedReference,
+		Digest:      createOpts.pushedDigest,
+		DryRun:      dryRun,
+		Assets:      createOpts.assets,
 	}
 
-	filePath := filepath.Join(c.ClusterStackReleaseDir, "hashes.json")
-	hashFile, err := os.Create(filepath.Clean(filePath))
-	if err != nil {
-		return fmt.Errorf("failed to create hash json: %w", err)
+	// Only OCI manifests have an artifact type.
+	if createOpts.pushedReference != "" && remote == "oci" {
+		result.ArtifactType = artifactType
 	}
-	defer hashFile.Close()
 
-	_, err = hashFile.Write(hashJSONData)
-	if err != nil {
-		return fmt.Errorf("failed to write current release hash: %w", err)
+	return result
+}
+
+// printCreated prints the created release or, for a dry run, the release that would be created.
+func printCreated(createOpts *CreateOptions) {
+	if !dryRun {
+		fmt.Printf("Created %s\n", createOpts.ClusterStackReleaseDir)
+		return
 	}
 
-	// Build all the templated output and put it in a tmp directory
-	if err := template.GenerateOutputFromTemplate(c.ClusterStackPath, "./.tmp/", c.Metadata); err != nil {
-		return fmt.Errorf("failed to generate tmp output: %w", err)
+	versions := createOpts.Metadata.Versions
+	fmt.Printf("Would create %s (dry run)\n", createOpts.ClusterStackReleaseDir)
+	fmt.Printf("  Kubernetes version:    %s\n", versions.Kubernetes)
+	fmt.Printf("  ClusterStack version:  %s\n", versions.ClusterStack)
+	fmt.Printf("  ClusterAddon version:  %s\n", versions.Components.ClusterAddon)
+	fmt.Printf("  NodeImage version:     %s\n", versions.Components.NodeImage)
+	fmt.Println("  Assets:")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, asset := range createOpts.assets {
+		mediaType := asset.MediaType
+		if mediaType == "" {
+			mediaType = "unknown, not published"
+		}
+		fmt.Fprintf(w, "    %s\t%s\n", asset.Name, mediaType)
 	}
+	_ = w.Flush()
+}
 
-	// Overwrite ClusterAddonVersion in cluster-addon/*/Chart.yaml
-	if err := overwriteClusterAddonVersion("./.tmp", c.Metadata.Versions.Components.ClusterAddon); err != nil {
-		return fmt.Errorf("failed to overwrite ClusterAddonVersion in tmp output: %w", err)
+// runCreate creates the release of the target.
+func runCreate(cmd *cobra.Command, target createTarget) (*CreateOptions, error) {
+	createOpts, cleanup, err := prepareCreate(cmd, target)
+	defer cleanup()
+	if err != nil {
+		return nil, err
 	}
 
-	// Overwrite ClusterClassVersion in cluster-class/Chart.yaml
-	clusterClassChartYaml := "./.tmp/cluster-class/Chart.yaml"
-	if err := overwriteVersionInFile(clusterClassChartYaml, c.Metadata.Versions.ClusterStack); err != nil {
-		return fmt.Errorf("failed to overwrite ClusterClassVersion in %s output: %w", clusterClassChartYaml, err)
+	if err := createOpts.create(cmd.Context()); err != nil {
+		return nil, err
 	}
 
-	// Package Helm from the tmp directory to the release directory
-	if err := template.CreatePackage("./.tmp/", c.ClusterStackReleaseDir, c.newClusterStackConvention, c.Config, c.Metadata); err != nil {
-		return fmt.Errorf("failed to create template package: %w", err)
+	return createOpts, nil
+}
+
+// prepareCreate computes the versions and the name of the release of the target without building it.
+// The returned function removes the temporary directory of the release and has to be called in any case.
+func prepareCreate(cmd *cobra.Command, target createTarget) (*CreateOptions, func(), error) {
+	clusterStackPath := target.clusterStackPath
+	cleanup := func() {}
+
+	if mode != stableMode && mode != alphaMode && mode != betaMode && mode != hashMode && mode != customMode {
+		return nil, cleanup, fmt.Errorf("mode %q is not supported please choose from - stable, alpha, beta, hash or custom", mode)
 	}
 
-	if c.newClusterStackConvention {
-		// Copy the clusteraddon.yaml config to release if new way
-		clusterAddonData, err := os.ReadFile(filepath.Join(c.ClusterStackPath, "clusteraddon.yaml"))
-		if err != nil {
-			return fmt.Errorf("failed to read clusteraddon.yaml: %w", err)
+	if latestReleaseDir != "" {
+		if mode != stableMode {
+			return nil, cleanup, fmt.Errorf("--latest-release-dir is only supported in stable mode")
 		}
 
-		if err := os.WriteFile(filepath.Join(c.ClusterStackReleaseDir, "clusteraddon.yaml"), clusterAddonData, os.FileMode(0o644)); err != nil {
-			return fmt.Errorf("failed to write clusteraddon.yaml: %w", err)
+		for _, name := range []string{"metadata.yaml", "hashes.json"} {
+			if _, err := os.Stat(filepath.Join(latestReleaseDir, name)); err != nil {
+				return nil, cleanup, fmt.Errorf("--latest-release-dir %s must contain %s: %w", latestReleaseDir, name, err)
+			}
 		}
-	} else {
-		// Copy the cluster-addon-values.yaml config to release if old way
-		clusterAddonData, err := os.ReadFile(filepath.Join(".tmp", "cluster-addon-values.yaml"))
+	}
+
+	if err := mediatype.Validate(artifactType); err != nil {
+		return nil, cleanup, fmt.Errorf("invalid --artifact-type: %w", err)
+	}
+
+	if artifactType != mediatype.ArtifactType && publish && remote != "oci" {
+		return nil, cleanup, fmt.Errorf("--artifact-type is only supported for remote oci")
+	}
+
+	if updateLatest && (!publish || mode != stableMode || remote != "oci") {
+		return nil, cleanup, fmt.Errorf("--update-latest is only supported with --publish in stable mode for remote oci")
+	}
+
+	if helmSign && helmKey == "" {
+		return nil, cleanup, fmt.Errorf("--helm-sign requires --helm-key")
+	}
+
+	packageCompression, err := template.ParseCompression(compression)
+	if err != nil {
+		return nil, cleanup, fmt.Errorf("failed to parse --compression: %w", err)
+	}
+
+	var templateValues map[string]interface{}
+	if valuesFile != "" {
+		templateValues, err = template.ReadValuesFile(valuesFile)
 		if err != nil {
-			return fmt.Errorf("failed to read cluster-addon-values.yaml: %w", err)
+			return nil, cleanup, fmt.Errorf("failed to read --values: %w", err)
 		}
+	}
 
-		if err := os.WriteFile(filepath.Join(c.ClusterStackReleaseDir, "cluster-addon-values.yaml"), clusterAddonData, os.FileMode(0o644)); err != nil {
-			return fmt.Errorf("failed to write cluster-addon-values.yaml: %w", err)
-		}
+	annotations, err := parseAnnotations(annotationFlags, force)
+	if err != nil {
+		return nil, cleanup, fmt.Errorf("failed to parse --annotation: %w", err)
 	}
 
-	// Put the final metadata file into the output directory.
-	metaDataByte, err := yaml.Marshal(c.Metadata)
+	workDir, err := os.MkdirTemp(tmpDir, "csctl-")
 	if err != nil {
-		return fmt.Errorf("failed to marshal metadata yaml: %w", err)
+		return nil, cleanup, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	// The marker tells csctl clean that the directory belongs to csctl create and since when.
+	if err := os.WriteFile(filepath.Join(workDir, tmpDirMarker), nil, os.FileMode(0o600)); err != nil {
+		return nil, cleanup, errors.Join(fmt.Errorf("failed to write %s: %w", tmpDirMarker, err), cleanTmpDirectory(workDir))
+	}
+	ctx := cmd.Context()
+	cleanup = func() {
+		if keepTmp {
+			logging.FromContext(ctx).Info("Kept temporary directory", "path", workDir)
+			return
+		}
+		if err := cleanTmpDirectory(workDir); err != nil {
+			logging.FromContext(ctx).Error("Failed to clean up", "error", err)
+		}
 	}
 
-	metadataFile, err := os.Create(filepath.Join(c.ClusterStackReleaseDir, "metadata.yaml"))
+	createOpts, err := GetCreateOptions(ctx, clusterStackPath, target.kubernetesVersion, workDir)
 	if err != nil {
-		return fmt.Errorf("failed to create metadata file: %w", err)
+		return nil, cleanup, fmt.Errorf("failed to create create options: %w", err)
+	}
+	createOpts.Compression = packageCompression
+	createOpts.TemplateValues = templateValues
+	createOpts.Annotations = annotations
+	createOpts.HelmOptions = template.HelmOptions{
+		UpdateDependencies: updateDependencies,
+		SkipLint:           skipLint,
+		StrictLint:         strictLint,
+		Sign:               helmSign,
+		Key:                helmKey,
+		Keyring:            helmKeyring,
+		PassphraseFile:     helmPassphraseFile,
 	}
-	defer metadataFile.Close()
 
-	if _, err := metadataFile.Write(metaDataByte); err != nil {
-		return fmt.Errorf("failed to write metadata: %w", err)
+	return createOpts, cleanup, nil
+}
+
+// create builds the release and publishes it with --publish, unless nothing changed since the latest release.
+func (c *CreateOptions) create(ctx context.Context) error {
+	// Validate if there any change or not
+	if !force {
+		if err := c.CurrentReleaseHash.ValidateWithLatestReleaseHash(c.LatestReleaseHash); err != nil {
+			if !errors.Is(err, hash.ErrNoChange) {
+				return fmt.Errorf("failed to compare with the latest release: %w", err)
+			}
+			switch {
+			case dryRun:
+				logging.FromContext(ctx).Warn("Nothing changed since the latest release. Without --dry-run, the release is only created with --force")
+			case hashNodeImages && c.latestReleasePath != "":
+				// node-images.yaml is only known after the provider plugin ran. generateRelease checks again.
+				logging.FromContext(ctx).Info("Cluster stack did not change since the latest release. Checking node-images.yaml")
+			default:
+				return hash.ErrNoChange
+			}
+		}
 	}
 
-	err = providerplugin.CreateNodeImages(c.Config,
-		c.ClusterStackPath,
-		c.ClusterStackReleaseDir,
-		c.NodeImageRegistry)
-	if err != nil {
-		return fmt.Errorf("providerplugin.CreateNodeImages() failed: %w", err)
+	if err := c.generateRelease(ctx); err != nil {
+		if errors.Is(err, hash.ErrNoChange) {
+			return err
+		}
+		return fmt.Errorf("failed to generate release: %w", err)
 	}
 
-	if publish {
-		if remote != "oci" {
-			return fmt.Errorf("not pushing assets. --publish is only implemented for remote OCI")
+	return nil
+}
+
+func (c *CreateOptions) generateRelease(ctx context.Context) error {
+	buildInfo := getBuildInfo()
+
+	// A dry run builds the release in the temporary directory to list its assets.
+	releaseDir := c.ClusterStackReleaseDir
+	if dryRun {
+		releaseDir = filepath.Join(c.tmpDir, "dry-run", c.releaseName)
+	}
+
+	setStage(fmt.Sprintf("building release %s, which includes calling the provider plugin", c.releaseName))
+	buildOpts := release.Options{
+		ClusterStackPath:          c.ClusterStackPath,
+		ReleaseDir:                releaseDir,
+		TmpDir:                    c.tmpDir,
+		NewClusterStackConvention: c.newClusterStackConvention,
+		Config:                    c.Config,
+		Metadata:                  c.Metadata,
+		ReleaseHash:               c.CurrentReleaseHash,
+		NodeImageRegistry:         c.NodeImageRegistry,
+		Compression:               c.Compression,
+		TemplateValues:            c.TemplateValues,
+		StrictTemplates:           strictTemplates,
+		HelmOptions:               c.HelmOptions,
+		Plugin:                    plugin,
+		PluginTimeout:             pluginTimeout,
+		BuildInfo:                 &buildInfo,
+		SkipNodeImages:            dryRun,
+	}
+	if _, err := release.Build(ctx, buildOpts); err != nil {
+		return fmt.Errorf("failed to build release: %w", err)
+	}
+
+	if hashNodeImages && !dryRun {
+		if err := c.addNodeImagesHash(ctx, buildOpts); err != nil {
+			return fmt.Errorf("failed to hash node-images.yaml: %w", err)
+		}
+
+		if !force && c.latestReleasePath != "" {
+			if err := c.CurrentReleaseHash.ValidateWithLatestReleaseHash(c.LatestReleaseHash); errors.Is(err, hash.ErrNoChange) {
+				if err := os.RemoveAll(releaseDir); err != nil {
+					return fmt.Errorf("failed to remove release directory %s: %w", releaseDir, err)
+				}
+				return hash.ErrNoChange
+			}
 		}
+	}
 
-		ociClient, err := oci.NewClient()
+	if dryRun {
+		assets, err := listReleaseAssets(releaseDir)
 		if err != nil {
-			return fmt.Errorf("failed to create new oci client: %w", err)
+			return err
 		}
+		c.assets = assets
+		return nil
+	}
+
+	if publish {
+		setStage(fmt.Sprintf("publishing release %s to the %s remote", c.releaseName, remote))
+
+		var (
+			pusher assetsclient.Pusher
+			err    error
+		)
 
-		var hashAnnotation string
-		if len(c.CurrentReleaseHash.ClusterStack) >= 7 {
-			hashAnnotation = c.CurrentReleaseHash.ClusterStack[:7]
+		switch remote {
+		case "github":
+			pusher, err = github.NewPusher(ctx, clientOptions.Options)
+		case "oci":
+			pusher, err = oci.NewClient(clientOptions)
+		case "gitlab":
+			pusher, err = gitlab.NewClient(clientOptions.Options)
+		default:
+			return fmt.Errorf("not pushing assets. --publish is not implemented for remote %q", remote)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create new %s client: %w", remote, err)
+		}
+
+		// A missing hash annotation must not prevent publishing the release.
+		hashAnnotation, err := c.CurrentReleaseHash.GetClusterStackHash()
+		if err != nil {
+			logging.FromContext(ctx).Warn("Not setting hash annotation", "error", err)
 		}
 
 		annotations := map[string]string{
 			"kubernetesVersion": c.Metadata.Versions.Kubernetes,
 			"hash":              hashAnnotation,
 		}
+		for key, value := range c.Annotations {
+			annotations[key] = value
+		}
+
+		pushed, digest, err := pushReleaseAssets(ctx, pusher, c.ClusterStackReleaseDir, c.releaseName, annotations, strict, !yes)
+		if err != nil {
+			return fmt.Errorf("failed to push release assets to the %s remote: %w", remote, err)
+		}
 
-		if err := pushReleaseAssets(ctx, ociClient, c.ClusterStackReleaseDir, c.releaseName, annotations); err != nil {
-			return fmt.Errorf("failed to push release assets to the oci registry: %w", err)
+		if pushed {
+			c.pushedReference = c.releaseName
+			if client, ok := pusher.(*oci.Client); ok {
+				c.pushedReference = fmt.Sprintf("%s:%s", client.Repository.Reference, c.releaseName)
+			}
+			c.pushedDigest = digest
+
+			if outputFormat == "" {
+				if digest != "" {
+					fmt.Printf("successfully pushed clusterstack release: %s with digest %s\n", c.releaseName, digest)
+				} else {
+					fmt.Printf("successfully pushed clusterstack release: %s \n", c.releaseName)
+				}
+			}
+		}
+
+		// Only move the latest tag to a release this run published. An existing release may be older than the
+		// one latest points to.
+		if updateLatest && mode == stableMode {
+			if !pushed {
+				logging.FromContext(ctx).Info("Not updating latest tag, as the release was not pushed", "release", c.releaseName)
+			} else if err := c.updateLatestTag(ctx, pusher); err != nil {
+				return fmt.Errorf("failed to update latest tag: %w", err)
+			}
 		}
 	}
 
 	return nil
 }
 
-func overwriteClusterAddonVersion(tmpDir, clusterAddonVersion string) error {
-	g := filepath.Join(tmpDir, "cluster-addon", "Chart.yaml")
-	files, err := filepath.Glob(g)
+// addNodeImagesHash adds the hash of node-images.yaml, which only exists after the provider plugin ran, to the
+// release hash. If there is a latest release, the versions are computed again with HandleStableMode, which bumps the
+// NodeImage version if node-images.yaml changed. Then the release is built again, so that the provider plugin creates
+// the node images for the new version.
+func (c *CreateOptions) addNodeImagesHash(ctx context.Context, buildOpts release.Options) error {
+	nodeImagesHash, err := hash.GetNodeImagesHash(buildOpts.ReleaseDir)
 	if err != nil {
-		return fmt.Errorf("glob for %s failed: %w", g, err)
+		return err
 	}
+	if nodeImagesHash == "" {
+		logging.FromContext(ctx).Info("Release has no node-images.yaml. Not hashing it")
+		return nil
+	}
+	c.CurrentReleaseHash.NodeImages = nodeImagesHash
 
-	for _, chartYaml := range files {
-		err := overwriteVersionInFile(chartYaml, clusterAddonVersion)
-		if err != nil {
-			return fmt.Errorf("failed to replace version in %s: %w", chartYaml, err)
+	if c.latestReleasePath != "" {
+		nodeImageVersion := c.Metadata.Versions.Components.NodeImage
+		if err := c.handleStableMode(ctx); err != nil {
+			return err
+		}
+		if err := c.applyVersionOverrides(ctx); err != nil {
+			return err
+		}
+
+		if c.Metadata.Versions.Components.NodeImage != nodeImageVersion {
+			logging.FromContext(ctx).Info("Building release again with the new NodeImage version", "version", c.Metadata.Versions.Components.NodeImage)
+			if err := os.RemoveAll(buildOpts.ReleaseDir); err != nil {
+				return fmt.Errorf("failed to remove release directory %s: %w", buildOpts.ReleaseDir, err)
+			}
+
+			buildOpts.Metadata = c.Metadata
+			buildOpts.ReleaseHash = c.CurrentReleaseHash
+			if _, err := release.Build(ctx, buildOpts); err != nil {
+				return fmt.Errorf("failed to build release again: %w", err)
+			}
+
+			// node-images.yaml of the new version is the one of the release.
+			c.CurrentReleaseHash.NodeImages, err = hash.GetNodeImagesHash(buildOpts.ReleaseDir)
+			if err != nil {
+				return err
+			}
 		}
 	}
-	return nil
+
+	return release.WriteReleaseHash(buildOpts.ReleaseDir, c.CurrentReleaseHash)
 }
 
-// overwriteVersionInFile replaces "version: v123" with newVersion.
-func overwriteVersionInFile(chartYaml, newVersion string) error {
-	chartYaml = filepath.Clean(chartYaml)
-	data, err := os.ReadFile(chartYaml)
-	if err != nil {
-		return fmt.Errorf("reading file failed: %w", err)
+// updateLatestTag points the floating latest tag to the published release.
+func (c *CreateOptions) updateLatestTag(ctx context.Context, pusher assetsclient.Pusher) error {
+	tagger, ok := pusher.(assetsclient.Tagger)
+	if !ok {
+		return fmt.Errorf("remote %q does not support tagging releases", remote)
 	}
 
-	m := make(map[string]interface{})
-	err = yaml.Unmarshal(data, m)
+	alias, err := getLatestAlias(c.Config)
 	if err != nil {
-		return fmt.Errorf("failed parsing: %w", err)
+		return fmt.Errorf("failed to get latest alias: %w", err)
 	}
 
-	v := m["version"]
-	oldVersion, ok := v.(string)
-	if !ok {
-		return fmt.Errorf("failed to read version in yaml")
+	if err := tagger.TagRelease(ctx, c.releaseName, alias); err != nil {
+		return fmt.Errorf("failed to tag release %q with %q: %w", c.releaseName, alias, err)
+	}
+
+	logging.FromContext(ctx).Info("Updated tag", "tag", alias, "release", c.releaseName)
+	return nil
+}
+
+// defaultHelmKeyring returns the default keyring of helm: $GNUPGHOME/pubring.gpg or ~/.gnupg/pubring.gpg.
+func defaultHelmKeyring() string {
+	if home := os.Getenv("GNUPGHOME"); home != "" {
+		return filepath.Join(home, "pubring.gpg")
 	}
+	return filepath.Join(homedir(), ".gnupg", "pubring.gpg")
+}
 
-	m["version"] = newVersion
-	fmt.Printf("%s updating version from %s to %s\n", chartYaml, oldVersion, newVersion)
-	out, err := yaml.Marshal(m)
+func homedir() string {
+	home, err := os.UserHomeDir()
 	if err != nil {
-		return fmt.Errorf("failed creating yaml: %w", err)
+		return ""
+	}
+	return home
+}
+
+// getLatestAlias returns the floating tag which points to the latest stable release.
+func getLatestAlias(config *clusterstack.CsctlConfig) (string, error) {
+	if latestAlias != "" {
+		return latestAlias, nil
 	}
-	err = os.WriteFile(chartYaml, out, 0o600)
+
+	kubernetesVersion, err := config.ParseKubernetesVersion()
 	if err != nil {
-		return fmt.Errorf("failed write yaml to file: %w", err)
+		return "", fmt.Errorf("failed to parse kubernetes version: %w", err)
 	}
-	return nil
+
+	return fmt.Sprintf("%s-%s-%s-latest", config.Config.Provider.Type, config.Config.ClusterStackName, kubernetesVersion.String()), nil
 }
 
-func cleanTmpDirectory() error {
-	if err := os.RemoveAll("./.tmp/"); err != nil {
+func cleanTmpDirectory(dir string) error {
+	if err := os.RemoveAll(dir); err != nil {
 		return fmt.Errorf("failed to remove tmp directory: %w", err)
 	}
 
 	return nil
 }
 
-func pushReleaseAssets(ctx context.Context, pusher assetsclient.Pusher, clusterStackReleasePath, releaseName string, annotations map[string]string) error {
+// pushReleaseAssets pushes all files of the release directory. Unknown files are skipped with a warning
+// or, if strict is set, abort the push. It returns whether the release was pushed and its digest.
+func pushReleaseAssets(ctx context.Context, pusher assetsclient.Pusher, clusterStackReleasePath, releaseName string, annotations map[string]string, strict, confirm bool) (bool, string, error) {
 	releaseAssets := []assetsclient.ReleaseAsset{}
 
-	ociclient, err := oci.NewClient()
+	found, err := pusher.FoundRelease(ctx, releaseName)
 	if err != nil {
-		return fmt.Errorf("error creating oci client: %w", err)
+		return false, "", fmt.Errorf("failed to check if release %s exists: %w", releaseName, err)
 	}
-
-	if ociclient.FoundRelease(ctx, releaseName) {
-		fmt.Printf("release tag \"%s\" found in oci registry. aborting push\n", releaseName)
-		return nil
+	if found {
+		logging.FromContext(ctx).Warn("Release tag found in remote repository. Aborting push", "release", releaseName)
+		return false, "", nil
 	}
 
 	files, err := os.ReadDir(clusterStackReleasePath)
 	if err != nil {
-		return fmt.Errorf("failed to read directory %s: %w", clusterStackReleasePath, err)
+		return false, "", fmt.Errorf("failed to read directory %s: %w", clusterStackReleasePath, err)
 	}
 
 	for _, file := range files {
-		if file.Type().IsRegular() {
-			releaseAssets = append(releaseAssets, assetsclient.ReleaseAsset{
-				FileName:  file.Name(),
-				MediaType: getMediaType(file.Name()),
-			})
+		if !file.Type().IsRegular() {
+			continue
+		}
+
+		mediaType, err := getMediaType(file.Name())
+		if err != nil {
+			if strict {
+				return false, "", fmt.Errorf("failed to get media type: %w", err)
+			}
+			logging.FromContext(ctx).Warn("Skipping file", "file", file.Name(), "error", err)
+			continue
 		}
+
+		releaseAssets = append(releaseAssets, assetsclient.ReleaseAsset{
+			FileName:  file.Name(),
+			MediaType: mediaType,
+		})
 	}
 
-	if err := pusher.PushReleaseAssets(ctx, releaseAssets, releaseName, clusterStackReleasePath, clusterStackArtifactType, annotations); err != nil {
-		return fmt.Errorf("failed to push release assets to oci registry: %w", err)
+	if confirm {
+		if err := confirmPublish(ctx, pusher, releaseAssets, clusterStackReleasePath, releaseName, annotations); err != nil {
+			return false, "", err
+		}
 	}
 
-	fmt.Printf("successfully pushed clusterstack release: %s:%s \n", ociclient.Repository.Reference.String(), releaseName)
-	return nil
+	digest, err := pusher.PushReleaseAssets(ctx, releaseAssets, releaseName, clusterStackReleasePath, artifactType, annotations)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to push release assets: %w", err)
+	}
+
+	return true, digest, nil
+}
+
+// confirmPublish shows the repository, the tag and the digest of the release and asks for confirmation on stdin.
+// The creation time is added to the annotations, so that the pushed manifest has the shown digest.
+func confirmPublish(ctx context.Context, pusher assetsclient.Pusher, releaseAssets []assetsclient.ReleaseAsset, clusterStackReleasePath, releaseName string, annotations map[string]string) error {
+	if !stdinIsTerminal() {
+		return fmt.Errorf("refusing to publish release %s without confirmation, because stdin is not a terminal. Use --yes to skip the confirmation", releaseName)
+	}
+
+	location := remote
+	if locator, ok := pusher.(assetsclient.Locator); ok {
+		location = locator.Location()
+	}
+
+	digest := "none, the remote has no digests"
+	if planner, ok := pusher.(assetsclient.Planner); ok {
+		if _, found := annotations[imagev1.AnnotationCreated]; !found {
+			annotations[imagev1.AnnotationCreated] = time.Now().UTC().Format(time.RFC3339)
+		}
+
+		var err error
+		digest, err = planner.ReleaseDigest(ctx, releaseAssets, clusterStackReleasePath, artifactType, annotations)
+		if err != nil {
+			return fmt.Errorf("failed to compute digest of release %s: %w", releaseName, err)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "About to publish release %s\n  repository: %s\n  tag:        %s\n  digest:     %s\n  assets:     %d\nPublish? [y/N] ",
+		releaseName, location, releaseName, digest, len(releaseAssets))
+
+	answer, err := stdinReader.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read confirmation: %w", err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	default:
+		return fmt.Errorf("publishing release %s was not confirmed", releaseName)
+	}
 }
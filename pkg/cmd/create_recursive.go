@@ -0,0 +1,249 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/SovereignCloudStack/csctl/pkg/clusterstack"
+	"github.com/SovereignCloudStack/csctl/pkg/hash"
+	"github.com/SovereignCloudStack/csctl/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+// createTarget is a release to create: a cluster stack and, if its csctl.yaml contains several
+// Kubernetes versions, the Kubernetes version of the release.
+type createTarget struct {
+	clusterStackPath  string
+	kubernetesVersion string
+}
+
+// getCreateTargets returns one target for each Kubernetes version of the cluster stack. The versions of
+// --kubernetes-version take precedence over the versions of csctl.yaml. If csctl.yaml cannot be read,
+// a single target is returned, so that the error is reported when the release is created.
+func getCreateTargets(clusterStackPath string) []createTarget {
+	versions := kubernetesVersions
+	if len(versions) == 0 {
+		config, err := clusterstack.ParseCsctlConfig(clusterStackPath)
+		if err != nil || len(config.Config.KubernetesVersions) == 0 {
+			return []createTarget{{clusterStackPath: clusterStackPath}}
+		}
+		versions = config.Config.KubernetesVersions
+	}
+
+	targets := make([]createTarget, 0, len(versions))
+	for _, kubernetesVersion := range versions {
+		targets = append(targets, createTarget{clusterStackPath: clusterStackPath, kubernetesVersion: kubernetesVersion})
+	}
+
+	return targets
+}
+
+// createRecursive creates a release for every cluster stack below root.
+func createRecursive(cmd *cobra.Command, root string) error {
+	if latestReleaseDir != "" {
+		return fmt.Errorf("--latest-release-dir cannot be used with --recursive")
+	}
+
+	clusterStackPaths, err := findClusterStacks(root)
+	if err != nil {
+		return err
+	}
+	if len(clusterStackPaths) == 0 {
+		return fmt.Errorf("no cluster stack found in %s: no directory contains a csctl.yaml", root)
+	}
+
+	var targets []createTarget
+	for _, clusterStackPath := range clusterStackPaths {
+		targets = append(targets, getCreateTargets(clusterStackPath)...)
+	}
+
+	return createMany(cmd, targets)
+}
+
+// preparedTarget is a target whose release name has been computed, but whose release has not been built yet.
+type preparedTarget struct {
+	target     createTarget
+	ctx        context.Context
+	createOpts *CreateOptions
+	err        error
+}
+
+// createMany creates the releases of all targets. Each target is processed independently.
+// Failures don't stop the other targets, but are reported at the end.
+// The names of all releases are computed before any release is built, so that targets which would write to the
+// same release directory fail before anything is written or published.
+func createMany(cmd *cobra.Command, targets []createTarget) error {
+	if len(targets) > 1 && latestReleaseDir != "" {
+		return fmt.Errorf("--latest-release-dir can only be used for a single release")
+	}
+
+	ctx := cmd.Context()
+	defer cmd.SetContext(ctx)
+
+	prepared := make([]*preparedTarget, 0, len(targets))
+	for _, target := range targets {
+		p := &preparedTarget{target: target}
+		prepared = append(prepared, p)
+		if ctx.Err() != nil {
+			p.err = ctx.Err()
+			continue
+		}
+
+		logger := logging.FromContext(ctx).With("clusterStack", target.clusterStackPath)
+		if target.kubernetesVersion != "" {
+			logger = logger.With("kubernetesVersion", target.kubernetesVersion)
+		}
+		p.ctx = logging.IntoContext(ctx, logger)
+		cmd.SetContext(p.ctx)
+
+		createOpts, cleanup, err := prepareCreate(cmd, target)
+		defer cleanup()
+		p.createOpts, p.err = createOpts, err
+	}
+
+	rejectDuplicateReleaseNames(prepared)
+
+	var (
+		results []createResult
+		failed  []string
+		created int
+	)
+
+	for _, p := range prepared {
+		name := p.target.String()
+		err := p.err
+		if err == nil {
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			} else {
+				logging.FromContext(p.ctx).Info("Creating release")
+				cmd.SetContext(p.ctx)
+				err = p.createOpts.create(p.ctx)
+			}
+		}
+
+		createOpts := p.createOpts
+		if err != nil {
+			createOpts = nil
+		}
+		result := newCreateResult(createOpts, err)
+		result.ClusterStackPath = p.target.clusterStackPath
+		results = append(results, result)
+
+		logger := logging.FromContext(ctx)
+		if p.ctx != nil {
+			logger = logging.FromContext(p.ctx)
+		}
+
+		switch {
+		case errors.Is(err, hash.ErrNoChange):
+			logger.Info("No change since the latest release")
+		case err != nil:
+			logger.Error("Failed to create release", "error", err)
+			failed = append(failed, name)
+		default:
+			created++
+			if outputFormat == "" {
+				printCreated(createOpts)
+			}
+		}
+	}
+
+	if outputFormat == "json" {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal results: %w", err)
+		}
+		fmt.Println(string(data))
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to create %d of %d releases: %s", len(failed), len(targets), strings.Join(failed, ", "))
+	}
+
+	if created == 0 {
+		return hash.ErrNoChange
+	}
+
+	return nil
+}
+
+// rejectDuplicateReleaseNames fails all targets whose release has the same name as the release of another target.
+// Two cluster stacks with the same provider, name, Kubernetes version and versions would write to the same release
+// directory and publish the same release.
+func rejectDuplicateReleaseNames(prepared []*preparedTarget) {
+	sources := map[string][]string{}
+	for _, p := range prepared {
+		if p.err == nil {
+			sources[p.createOpts.releaseName] = append(sources[p.createOpts.releaseName], p.target.String())
+		}
+	}
+
+	for _, p := range prepared {
+		if p.err != nil {
+			continue
+		}
+		if names := sources[p.createOpts.releaseName]; len(names) > 1 {
+			p.err = fmt.Errorf("release %s would be created from several targets: %s", p.createOpts.releaseName, strings.Join(names, ", "))
+		}
+	}
+}
+
+// String returns the cluster stack path and, if set, the Kubernetes version of the target.
+func (t createTarget) String() string {
+	if t.kubernetesVersion == "" {
+		return t.clusterStackPath
+	}
+	return fmt.Sprintf("%s (%s)", t.clusterStackPath, t.kubernetesVersion)
+}
+
+// findClusterStacks returns all directories below root that contain a csctl.yaml, including root itself.
+// Hidden directories are skipped, and cluster stacks are not searched within other cluster stacks.
+func findClusterStacks(root string) ([]string, error) {
+	var clusterStackPaths []string
+
+	if err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+
+		if _, err := os.Stat(filepath.Join(path, "csctl.yaml")); err == nil {
+			clusterStackPaths = append(clusterStackPaths, path)
+			return filepath.SkipDir
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to search cluster stacks in %s: %w", root, err)
+	}
+
+	return clusterStackPaths, nil
+}
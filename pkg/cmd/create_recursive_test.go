@@ -0,0 +1,46 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRejectDuplicateReleaseNames(t *testing.T) {
+	errFailed := errors.New("failed")
+	prepared := []*preparedTarget{
+		{target: createTarget{clusterStackPath: "a"}, createOpts: &CreateOptions{releaseName: "docker-ferrol-1-27-v1"}},
+		{target: createTarget{clusterStackPath: "b"}, createOpts: &CreateOptions{releaseName: "docker-ferrol-1-27-v1"}},
+		{target: createTarget{clusterStackPath: "c"}, createOpts: &CreateOptions{releaseName: "docker-valencia-1-27-v1"}},
+		{target: createTarget{clusterStackPath: "d"}, err: errFailed},
+	}
+
+	rejectDuplicateReleaseNames(prepared)
+
+	for _, p := range prepared[:2] {
+		if p.err == nil {
+			t.Errorf("target %s with duplicate release name was not rejected", p.target)
+		}
+	}
+	if prepared[2].err != nil {
+		t.Errorf("target c was rejected: %v", prepared[2].err)
+	}
+	if !errors.Is(prepared[3].err, errFailed) {
+		t.Errorf("error of target d = %v, want %v", prepared[3].err, errFailed)
+	}
+}
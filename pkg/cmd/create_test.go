@@ -0,0 +1,138 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/SovereignCloudStack/csctl/pkg/assetsclient"
+	"github.com/SovereignCloudStack/csctl/pkg/hash"
+	"github.com/SovereignCloudStack/csctl/pkg/mediatype"
+)
+
+func TestCreateStopsWithoutChange(t *testing.T) {
+	latest := hash.ReleaseHash{
+		HashVersion:        hash.CurrentVersion,
+		ClusterStack:       "stack",
+		ClusterClassDir:    "class",
+		ClusterAddonDir:    "addon",
+		ClusterAddonValues: "values",
+		NodeImageDir:       "nodeimagedir",
+	}
+
+	tests := []struct {
+		name       string
+		change     func(current *hash.ReleaseHash)
+		wantChange bool
+	}{
+		{
+			name:   "nothing changed",
+			change: func(*hash.ReleaseHash) {},
+		},
+		{
+			name:       "only node images changed",
+			change:     func(current *hash.ReleaseHash) { current.NodeImageDir = "othernodeimagedir" },
+			wantChange: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := latest
+			tt.change(&current)
+
+			// The cluster stack is empty, so building the release fails right after the comparison.
+			c := &CreateOptions{
+				ClusterStackPath:   t.TempDir(),
+				CurrentReleaseHash: current,
+				LatestReleaseHash:  latest,
+				tmpDir:             t.TempDir(),
+			}
+
+			err := c.create(context.Background())
+			if tt.wantChange && errors.Is(err, hash.ErrNoChange) {
+				t.Errorf("create() = %v, want it to build the release", err)
+			}
+			if !tt.wantChange && !errors.Is(err, hash.ErrNoChange) {
+				t.Errorf("create() = %v, want ErrNoChange", err)
+			}
+		})
+	}
+}
+
+// fakePusher records the release assets it is asked to push.
+type fakePusher struct {
+	pushed []assetsclient.ReleaseAsset
+}
+
+func (p *fakePusher) PushReleaseAssets(_ context.Context, releaseAssets []assetsclient.ReleaseAsset, _, _, _ string, _ map[string]string) (string, error) {
+	p.pushed = releaseAssets
+	return "sha256:digest", nil
+}
+
+func (*fakePusher) FoundRelease(context.Context, string) (bool, error) {
+	return false, nil
+}
+
+func TestPushReleaseAssetsUnknownFile(t *testing.T) {
+	releaseDir := t.TempDir()
+	for _, name := range []string{"metadata.yaml", "clusteraddon.yaml", "docker-ferrol-1-27-cluster-class-v1.tgz", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(releaseDir, name), []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("skipped", func(t *testing.T) {
+		pusher := &fakePusher{}
+
+		pushed, _, err := pushReleaseAssets(context.Background(), pusher, releaseDir, "docker-ferrol-1-27-v1", map[string]string{}, false, false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !pushed {
+			t.Fatal("release was not pushed")
+		}
+
+		var names []string
+		for _, asset := range pusher.pushed {
+			names = append(names, asset.FileName)
+		}
+		if slices.Contains(names, "notes.txt") {
+			t.Errorf("unknown file notes.txt was pushed: %v", names)
+		}
+		if len(names) != 3 {
+			t.Errorf("got %d pushed files, want 3: %v", len(names), names)
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		pusher := &fakePusher{}
+
+		_, _, err := pushReleaseAssets(context.Background(), pusher, releaseDir, "docker-ferrol-1-27-v1", map[string]string{}, true, false)
+		if !errors.Is(err, mediatype.ErrUnknown) {
+			t.Errorf("pushReleaseAssets() = %v, want ErrUnknown", err)
+		}
+		if pusher.pushed != nil {
+			t.Errorf("release was pushed in strict mode: %v", pusher.pushed)
+		}
+	})
+}
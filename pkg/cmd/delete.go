@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	csoclusterstack "github.com/SovereignCloudStack/cluster-stack-operator/pkg/clusterstack"
+	"github.com/SovereignCloudStack/csctl/pkg/assetsclient"
+	"github.com/SovereignCloudStack/csctl/pkg/assetsclient/oci"
+	"github.com/spf13/cobra"
+)
+
+var (
+	deleteRemote string
+	deleteYes    bool
+	deleteForce  bool
+)
+
+var deleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Deletes a cluster stack release from a remote repository",
+	Long: `It deletes the release with the given tag from the remote repository.
+	Only tags of cluster stack releases can be deleted, unless --force is given.`,
+	Example:      `csctl delete docker-ferrol-1-27-v0-sha-umrn3fe --remote oci --yes`,
+	RunE:         deleteAction,
+	SilenceUsage: true,
+}
+
+func init() {
+	deleteCmd.Flags().StringVar(&deleteRemote, "remote", "oci", "Which remote repository to use and thus which credentials are required. Currently supported is 'oci'.")
+	deleteCmd.Flags().BoolVarP(&deleteYes, "yes", "y", false, "Confirm the deletion of the release")
+	deleteCmd.Flags().BoolVar(&deleteForce, "force", false, "Delete the tag even if it is not a cluster stack release")
+}
+
+func deleteAction(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("please provide a valid command, delete only accept one argument to the release tag")
+	}
+	releaseTag := args[0]
+
+	if _, err := csoclusterstack.NewFromClusterStackReleaseProperties(releaseTag); err != nil && !deleteForce {
+		return fmt.Errorf("tag %q is not a cluster stack release, use --force to delete it anyway: %w", releaseTag, err)
+	}
+
+	if !deleteYes {
+		return fmt.Errorf("not deleting release %q. please confirm the deletion with --yes", releaseTag)
+	}
+
+	var remoteFactory assetsclient.Factory
+
+	switch deleteRemote {
+	case "oci":
+		remoteFactory = oci.NewFactory(clientOptions)
+	default:
+		return fmt.Errorf("remote %q is not supported please choose from - oci", deleteRemote)
+	}
+
+	ac, err := remoteFactory.NewClient(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("failed to create new asset client: %w", err)
+	}
+
+	deleter, ok := ac.(assetsclient.Deleter)
+	if !ok {
+		return fmt.Errorf("remote %q does not support deleting releases", deleteRemote)
+	}
+
+	if err := deleter.DeleteRelease(cmd.Context(), releaseTag); err != nil {
+		return fmt.Errorf("failed to delete release %q: %w", releaseTag, err)
+	}
+
+	fmt.Printf("Deleted release %s\n", releaseTag)
+
+	return nil
+}
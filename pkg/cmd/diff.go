@@ -0,0 +1,184 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/SovereignCloudStack/csctl/pkg/assetsclient"
+	"github.com/SovereignCloudStack/csctl/pkg/assetsclient/github"
+	"github.com/SovereignCloudStack/csctl/pkg/assetsclient/gitlab"
+	"github.com/SovereignCloudStack/csctl/pkg/assetsclient/oci"
+	"github.com/SovereignCloudStack/csctl/pkg/clusterstack"
+	"github.com/SovereignCloudStack/csctl/pkg/hash"
+	"github.com/SovereignCloudStack/csctl/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffRemote string
+	diffMode   string
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Shows what changed in a cluster stack since the latest release",
+	Long: `It downloads the latest release of the cluster stack from the remote repository and compares
+	its hashes with the hashes of the cluster stack. This shows which components would get a new version.`,
+	Example:      `csctl diff tests/cluster-stacks/docker/ferrol --remote oci`,
+	RunE:         diffAction,
+	SilenceUsage: true,
+}
+
+func init() {
+	diffCmd.Flags().StringVar(&diffRemote, "remote", "github", "Which remote repository to use and thus which credentials are required. Currently supported are 'github', 'oci' and 'gitlab'.")
+	diffCmd.Flags().StringVarP(&diffMode, "mode", "m", stableMode, "The channel of the latest release to compare with - stable, alpha or beta")
+}
+
+func diffAction(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("please provide a valid command, diff only accept one argument to path to the cluster stacks")
+	}
+	clusterStackPath := args[0]
+
+	if diffMode != stableMode && diffMode != alphaMode && diffMode != betaMode {
+		return fmt.Errorf("mode %q is not supported please choose from - stable, alpha or beta", diffMode)
+	}
+
+	config, err := clusterstack.GetCsctlConfig(clusterStackPath)
+	if err != nil {
+		return fmt.Errorf("failed to get config: %w", err)
+	}
+
+	currentHash, err := hash.GetHash(cmd.Context(), clusterStackPath)
+	if err != nil {
+		return fmt.Errorf("failed to get hash: %w", err)
+	}
+
+	var remoteFactory assetsclient.Factory
+
+	switch diffRemote {
+	case "github":
+		remoteFactory = github.NewFactoryForAssets(clientOptions.Options, releaseAssetNames...)
+	case "oci":
+		remoteFactory = oci.NewFactory(clientOptions)
+	case "gitlab":
+		remoteFactory = gitlab.NewFactoryForAssets(clientOptions.Options, releaseAssetNames...)
+	default:
+		return fmt.Errorf("remote %q is not supported please choose from - github, oci or gitlab", diffRemote)
+	}
+
+	ac, err := remoteFactory.NewClient(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("failed to create new asset client: %w", err)
+	}
+
+	kubernetesVersions := config.GetKubernetesVersions()
+	if len(kubernetesVersions) == 1 {
+		return diffKubernetesVersion(cmd.Context(), config, currentHash, ac)
+	}
+
+	for _, kubernetesVersion := range kubernetesVersions {
+		versionConfig, err := config.WithKubernetesVersion(kubernetesVersion)
+		if err != nil {
+			return fmt.Errorf("failed to select kubernetes version: %w", err)
+		}
+
+		fmt.Printf("Kubernetes version %s:\n", kubernetesVersion)
+		if err := diffKubernetesVersion(cmd.Context(), versionConfig, currentHash, ac); err != nil {
+			return fmt.Errorf("failed to diff kubernetes version %s: %w", kubernetesVersion, err)
+		}
+	}
+
+	return nil
+}
+
+// diffKubernetesVersion prints what changed since the latest release of the kubernetes version of config.
+func diffKubernetesVersion(ctx context.Context, config *clusterstack.CsctlConfig, currentHash hash.ReleaseHash, ac assetsclient.Client) error {
+	latestRepoRelease, err := getLatestReleaseFromRemoteRepository(ctx, diffMode, config, ac)
+	if err != nil {
+		return fmt.Errorf("failed to get latest release form remote repository: %w", err)
+	}
+
+	if latestRepoRelease == "" {
+		fmt.Printf("No %s release found. All components are new.\n", diffMode)
+		return nil
+	}
+	logging.FromContext(ctx).Info("Latest release found", "release", latestRepoRelease)
+
+	downloadDir, err := os.MkdirTemp("", "csctl-diff-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(downloadDir)
+
+	releaseDir := filepath.Join(downloadDir, "release")
+	if err := downloadReleaseAssets(ctx, latestRepoRelease, releaseDir, ac); err != nil {
+		return fmt.Errorf("failed to download release asset: %w", err)
+	}
+
+	latestHash, err := hash.ParseReleaseHash(filepath.Join(releaseDir, "hashes.json"))
+	if err != nil {
+		return fmt.Errorf("failed to read hash from the release: %w", err)
+	}
+
+	metadata, err := clusterstack.ParseMetaData(releaseDir)
+	if err != nil {
+		return fmt.Errorf("failed to parse metadata: %w", err)
+	}
+
+	if err := currentHash.CheckVersion(latestHash); err != nil {
+		return fmt.Errorf("cannot compare with release %s: %w", latestRepoRelease, err)
+	}
+
+	printHashDiff(currentHash, latestHash, metadata)
+
+	return nil
+}
+
+// printHashDiff prints which components changed in the same way as HandleStableMode.
+func printHashDiff(currentHash, latestHash hash.ReleaseHash, metadata *clusterstack.MetaData) {
+	if currentHash.ClusterClassChanged(latestHash) {
+		fmt.Printf("ClusterClass changed [clusterClassDir]: Version %s would be bumped\n", metadata.Versions.ClusterStack)
+	}
+
+	var changed []string
+	if currentHash.ClusterAddonDir != latestHash.ClusterAddonDir {
+		changed = append(changed, "clusterAddonDir")
+	}
+	if currentHash.ClusterAddonValues != latestHash.ClusterAddonValues {
+		changed = append(changed, "clusterAddonValues")
+	}
+
+	if len(changed) > 0 {
+		fmt.Printf("ClusterAddon changed %v: Version %s would be bumped\n", changed, metadata.Versions.Components.ClusterAddon)
+	} else {
+		fmt.Printf("ClusterAddon Version unchanged: %s\n", metadata.Versions.Components.ClusterAddon)
+	}
+
+	switch {
+	case currentHash.NodeImageDir != latestHash.NodeImageDir:
+		fmt.Printf("NodeImage changed [nodeImageDir]: Version %s would be bumped\n", metadata.Versions.Components.NodeImage)
+	case metadata.Versions.Components.NodeImage == "":
+		fmt.Println("No NodeImage Version.")
+	default:
+		fmt.Printf("NodeImage Version unchanged: %s\n", metadata.Versions.Components.NodeImage)
+	}
+}
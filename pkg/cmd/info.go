@@ -0,0 +1,167 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/SovereignCloudStack/csctl/pkg/assetsclient"
+	"github.com/SovereignCloudStack/csctl/pkg/assetsclient/oci"
+	"github.com/SovereignCloudStack/csctl/pkg/clusterstack"
+	"github.com/SovereignCloudStack/csctl/pkg/logging"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// maxInfoMetadataSize is the maximum size of metadata.yaml that is fetched by the info command.
+const maxInfoMetadataSize = 64 * 1024
+
+var (
+	infoRemote string
+	infoOutput string
+)
+
+// releaseInfo is the json representation of the information about a release.
+type releaseInfo struct {
+	Name         string                 `json:"name"`
+	ArtifactType string                 `json:"artifactType,omitempty"`
+	Annotations  map[string]string      `json:"annotations"`
+	Metadata     *clusterstack.MetaData `json:"metadata,omitempty"`
+}
+
+var infoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Shows the metadata of a cluster stack release in a remote repository",
+	Long: `It fetches the manifest of the release with the given tag and shows its artifact type and annotations,
+	like the Kubernetes version and the hash. If the release contains a small metadata.yaml, it is shown as well.
+	The other release assets are not downloaded.`,
+	Example:      `csctl info docker-ferrol-1-27-v1 --remote oci -o json`,
+	RunE:         infoAction,
+	SilenceUsage: true,
+}
+
+func init() {
+	infoCmd.Flags().StringVar(&infoRemote, "remote", "oci", "Which remote repository to use and thus which credentials are required. Currently supported is 'oci'.")
+	infoCmd.Flags().StringVarP(&infoOutput, "output", "o", "", "Output format. One of '' or 'json'")
+}
+
+func infoAction(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("please provide a valid command, info only accept one argument to the release tag")
+	}
+	releaseTag := args[0]
+
+	if infoOutput != "" && infoOutput != "json" {
+		return fmt.Errorf("output format %q is not supported please choose from - json", infoOutput)
+	}
+
+	var remoteFactory assetsclient.Factory
+
+	switch infoRemote {
+	case "oci":
+		remoteFactory = oci.NewFactory(clientOptions)
+	default:
+		return fmt.Errorf("remote %q is not supported please choose from - oci", infoRemote)
+	}
+
+	ac, err := remoteFactory.NewClient(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("failed to create new asset client: %w", err)
+	}
+
+	getter, ok := ac.(assetsclient.MetadataGetter)
+	if !ok {
+		return fmt.Errorf("remote %q does not support showing release metadata", infoRemote)
+	}
+
+	annotations, err := getter.GetReleaseMetadata(cmd.Context(), releaseTag)
+	if err != nil {
+		return fmt.Errorf("failed to get metadata of release %q: %w", releaseTag, err)
+	}
+
+	artifactType, err := getter.GetReleaseArtifactType(cmd.Context(), releaseTag)
+	if err != nil {
+		return fmt.Errorf("failed to get artifact type of release %q: %w", releaseTag, err)
+	}
+
+	info := releaseInfo{
+		Name:         releaseTag,
+		ArtifactType: artifactType,
+		Annotations:  annotations,
+	}
+
+	// metadata.yaml is optional information. Releases without it are still shown.
+	data, err := getter.GetReleaseFile(cmd.Context(), releaseTag, "metadata.yaml", maxInfoMetadataSize)
+	switch {
+	case err != nil:
+		logging.FromContext(cmd.Context()).Warn("Not showing metadata.yaml", "error", err)
+	case data == nil:
+		logging.FromContext(cmd.Context()).Warn("metadata.yaml is too large. Not showing it", "release", releaseTag, "maxSize", maxInfoMetadataSize)
+	default:
+		metadata := &clusterstack.MetaData{}
+		if err := yaml.Unmarshal(data, metadata); err != nil {
+			return fmt.Errorf("failed to unmarshal metadata.yaml of release %q: %w", releaseTag, err)
+		}
+		info.Metadata = metadata
+	}
+
+	if infoOutput == "json" {
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal release info: %w", err)
+		}
+		fmt.Println(string(data))
+
+		return nil
+	}
+
+	printReleaseInfo(info)
+
+	return nil
+}
+
+func printReleaseInfo(info releaseInfo) {
+	fmt.Printf("Release: %s\n", info.Name)
+	if info.ArtifactType != "" {
+		fmt.Printf("Artifact type: %s\n", info.ArtifactType)
+	}
+
+	keys := make([]string, 0, len(info.Annotations))
+	for key := range info.Annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Println("Annotations:")
+	for _, key := range keys {
+		fmt.Printf("  %s: %s\n", key, info.Annotations[key])
+	}
+
+	if info.Metadata == nil {
+		return
+	}
+
+	fmt.Println("Versions:")
+	fmt.Printf("  clusterStack: %s\n", info.Metadata.Versions.ClusterStack)
+	fmt.Printf("  kubernetes: %s\n", info.Metadata.Versions.Kubernetes)
+	fmt.Printf("  clusterAddon: %s\n", info.Metadata.Versions.Components.ClusterAddon)
+	if info.Metadata.Versions.Components.NodeImage != "" {
+		fmt.Printf("  nodeImage: %s\n", info.Metadata.Versions.Components.NodeImage)
+	}
+}
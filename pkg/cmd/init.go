@@ -0,0 +1,158 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/SovereignCloudStack/csctl/pkg/clusterstack"
+	"github.com/SovereignCloudStack/csctl/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+var (
+	initProvider          string
+	initName              string
+	initKubernetesVersion string
+	initOldConvention     bool
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Creates the skeleton of a new cluster stack",
+	Long: `It creates the directory layout of a new cluster stack with csctl.yaml, the cluster class chart
+	and the cluster addon chart. By default, the cluster addons are configured with clusteraddon.yaml.
+	With --old-convention, cluster-addon-values.yaml is used instead.`,
+	Example:      `csctl init my-cluster-stacks/docker/ferrol --provider docker --name ferrol --kubernetes-version v1.27.7`,
+	RunE:         initAction,
+	SilenceUsage: true,
+}
+
+func init() {
+	initCmd.Flags().StringVar(&initProvider, "provider", "", "The provider type of the cluster stack, e.g. docker")
+	initCmd.Flags().StringVar(&initName, "name", "", "The name of the cluster stack, e.g. ferrol")
+	initCmd.Flags().StringVar(&initKubernetesVersion, "kubernetes-version", "", "The Kubernetes version of the cluster stack, e.g. v1.27.7")
+	initCmd.Flags().BoolVar(&initOldConvention, "old-convention", false, "Configure the cluster addons with cluster-addon-values.yaml instead of clusteraddon.yaml")
+}
+
+const (
+	initCsctlYaml = `apiVersion: csctl.clusterstack.x-k8s.io/v1alpha1
+config:
+  kubernetesVersion: %[3]s
+  clusterStackName: %[2]s
+  provider:
+    type: %[1]s
+    apiVersion: %[1]s.csctl.clusterstack.x-k8s.io/v1alpha1
+`
+
+	initChartYaml = `apiVersion: v2
+description: %[2]s
+name: %[1]s
+type: application
+# version will be overwritten by csctl
+version: v0
+`
+
+	initClusterAddonYaml = `apiVersion: clusteraddonconfig.x-k8s.io/v1alpha1
+clusterAddonVersion: clusteraddons.clusterstack.x-k8s.io/v1alpha1
+addonStages:
+  AfterControlPlaneInitialized: []
+`
+
+	initClusterAddonValuesYaml = `values: |
+`
+)
+
+func initAction(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("please provide a valid command, init only accept one argument to the path of the new cluster stack")
+	}
+	clusterStackPath := args[0]
+
+	if initProvider == "" || initName == "" || initKubernetesVersion == "" {
+		return fmt.Errorf("please specify --provider, --name and --kubernetes-version")
+	}
+
+	if _, err := os.Stat(filepath.Join(clusterStackPath, "csctl.yaml")); err == nil {
+		return fmt.Errorf("%s already contains a cluster stack", clusterStackPath)
+	}
+
+	files := map[string]string{
+		"csctl.yaml": fmt.Sprintf(initCsctlYaml, initProvider, initName, initKubernetesVersion),
+	}
+
+	if err := writeInitFiles(clusterStackPath, files); err != nil {
+		return err
+	}
+
+	// Validate csctl.yaml before writing anything else, so that invalid flags leave no skeleton behind.
+	config, err := clusterstack.GetCsctlConfig(clusterStackPath)
+	if err != nil {
+		if err := os.Remove(filepath.Join(clusterStackPath, "csctl.yaml")); err != nil {
+			logging.FromContext(cmd.Context()).Error("Failed to remove invalid csctl.yaml", "error", err)
+		}
+		return fmt.Errorf("invalid cluster stack: %w", err)
+	}
+
+	kubernetesVersion, err := config.ParseKubernetesVersion()
+	if err != nil {
+		return fmt.Errorf("failed to parse kubernetes version: %w", err)
+	}
+	prefix := fmt.Sprintf("%s-%s-%s", initProvider, initName, kubernetesVersion.String())
+
+	files = map[string]string{
+		filepath.Join("cluster-class", "Chart.yaml"):                      fmt.Sprintf(initChartYaml, prefix+"-cluster-class", "Cluster class of the cluster stack "+initName),
+		filepath.Join("cluster-class", "values.yaml"):                     "",
+		filepath.Join("cluster-class", "templates", "cluster-class.yaml"): "",
+	}
+
+	if initOldConvention {
+		files[filepath.Join("cluster-addon", "Chart.yaml")] = fmt.Sprintf(initChartYaml, prefix+"-cluster-addon", "Cluster addons of the cluster stack "+initName)
+		files[filepath.Join("cluster-addon", "values.yaml")] = ""
+		files["cluster-addon-values.yaml"] = initClusterAddonValuesYaml
+	} else {
+		if err := os.MkdirAll(filepath.Join(clusterStackPath, "cluster-addon"), os.ModePerm); err != nil {
+			return fmt.Errorf("failed to create cluster-addon directory: %w", err)
+		}
+		files["clusteraddon.yaml"] = initClusterAddonYaml
+	}
+
+	if err := writeInitFiles(clusterStackPath, files); err != nil {
+		return err
+	}
+
+	fmt.Printf("Created cluster stack %s in %s\n", prefix, clusterStackPath)
+	return nil
+}
+
+func writeInitFiles(clusterStackPath string, files map[string]string) error {
+	for name, content := range files {
+		path := filepath.Join(clusterStackPath, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", name, err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), os.FileMode(0o644)); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
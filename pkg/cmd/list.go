@@ -0,0 +1,224 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	csoclusterstack "github.com/SovereignCloudStack/cluster-stack-operator/pkg/clusterstack"
+	"github.com/SovereignCloudStack/cluster-stack-operator/pkg/kubernetesversion"
+	"github.com/SovereignCloudStack/csctl/pkg/assetsclient"
+	"github.com/SovereignCloudStack/csctl/pkg/assetsclient/github"
+	"github.com/SovereignCloudStack/csctl/pkg/assetsclient/gitlab"
+	"github.com/SovereignCloudStack/csctl/pkg/assetsclient/oci"
+	"github.com/SovereignCloudStack/csctl/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+var (
+	listRemote            string
+	listProvider          string
+	listClusterStackName  string
+	listKubernetesVersion string
+	listOutput            string
+)
+
+// listedRelease is the json representation of a listed release.
+type listedRelease struct {
+	Name              string `json:"name"`
+	Provider          string `json:"provider"`
+	ClusterStackName  string `json:"clusterStackName"`
+	KubernetesVersion string `json:"kubernetesVersion"`
+	Version           string `json:"version"`
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the cluster stack releases of a remote repository",
+	Long: `It lists all cluster stack releases that are found in the remote repository.
+	The releases can be filtered by provider, cluster stack name and Kubernetes version.`,
+	Example: `csctl list --remote oci --provider docker --cluster-stack-name ferrol
+
+csctl list --remote github --kubernetes-version 1.27 -o json
+
+csctl list --remote oci -o wide`,
+	RunE:         listAction,
+	SilenceUsage: true,
+}
+
+func init() {
+	listCmd.Flags().StringVar(&listRemote, "remote", "github", "Which remote repository to use and thus which credentials are required. Currently supported are 'github', 'oci' and 'gitlab'.")
+	listCmd.Flags().StringVar(&listProvider, "provider", "", "Only list releases of this provider")
+	listCmd.Flags().StringVar(&listClusterStackName, "cluster-stack-name", "", "Only list releases of this cluster stack")
+	listCmd.Flags().StringVar(&listKubernetesVersion, "kubernetes-version", "", "Only list releases of this Kubernetes version. For example 1.27 or v1.27.7")
+	listCmd.Flags().StringVarP(&listOutput, "output", "o", "", "Output format. One of '', 'json' or 'wide'. 'wide' shows the annotations of the releases and is only supported for oci")
+}
+
+func listAction(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("list does not accept any arguments")
+	}
+
+	if listOutput != "" && listOutput != "json" && listOutput != "wide" {
+		return fmt.Errorf("output format %q is not supported please choose from - json or wide", listOutput)
+	}
+
+	filter := releaseFilter{
+		provider:         listProvider,
+		clusterStackName: listClusterStackName,
+	}
+
+	if listKubernetesVersion != "" {
+		kubernetesVersion, err := parseKubernetesVersionFilter(listKubernetesVersion)
+		if err != nil {
+			return fmt.Errorf("failed to parse kubernetes version %q: %w", listKubernetesVersion, err)
+		}
+		filter.kubernetesVersion = kubernetesVersion.StringWithDot()
+	}
+
+	var remoteFactory assetsclient.Factory
+
+	switch listRemote {
+	case "github":
+		remoteFactory = github.NewFactory(clientOptions.Options)
+	case "oci":
+		remoteFactory = oci.NewFactory(clientOptions)
+	case "gitlab":
+		remoteFactory = gitlab.NewFactory(clientOptions.Options)
+	default:
+		return fmt.Errorf("remote %q is not supported please choose from - github, oci or gitlab", listRemote)
+	}
+
+	ac, err := remoteFactory.NewClient(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("failed to create new asset client: %w", err)
+	}
+
+	var (
+		releases    []string
+		annotations map[string]map[string]string
+	)
+
+	if listOutput == "wide" {
+		getter, ok := ac.(assetsclient.MetadataGetter)
+		if !ok {
+			return fmt.Errorf("remote %q does not support listing releases with annotations", listRemote)
+		}
+
+		// Only the manifests of listed releases are fetched.
+		match := func(tag string) bool {
+			clusterStack, err := csoclusterstack.NewFromClusterStackReleaseProperties(tag)
+			return err == nil && filter.matches(clusterStack)
+		}
+
+		releasesWithMetadata, err := getter.ListReleaseWithMetadata(cmd.Context(), match)
+		if err != nil {
+			return fmt.Errorf("failed to list releases: %w", err)
+		}
+
+		annotations = make(map[string]map[string]string, len(releasesWithMetadata))
+		for _, release := range releasesWithMetadata {
+			if release.Err != nil {
+				logging.FromContext(cmd.Context()).Warn("Failed to get annotations of release", "release", release.Tag, "error", release.Err)
+			}
+			releases = append(releases, release.Tag)
+			annotations[release.Tag] = release.Annotations
+		}
+	} else {
+		releases, err = ac.ListRelease(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to list releases: %w", err)
+		}
+	}
+
+	var clusterStacks csoclusterstack.ClusterStacks
+
+	for _, release := range releases {
+		// Releases which are not cluster stack releases are not listed.
+		clusterStack, err := csoclusterstack.NewFromClusterStackReleaseProperties(release)
+		if err != nil {
+			continue
+		}
+
+		if filter.matches(clusterStack) {
+			clusterStacks = append(clusterStacks, clusterStack)
+		}
+	}
+
+	sortClusterStacks(clusterStacks)
+
+	if listOutput == "json" {
+		listedReleases := make([]listedRelease, 0, len(clusterStacks))
+		for i := range clusterStacks {
+			listedReleases = append(listedReleases, listedRelease{
+				Name:              clusterStacks[i].String(),
+				Provider:          clusterStacks[i].Provider,
+				ClusterStackName:  clusterStacks[i].Name,
+				KubernetesVersion: clusterStacks[i].KubernetesVersion.StringWithDot(),
+				Version:           clusterStacks[i].Version.StringWithDot(),
+			})
+		}
+
+		data, err := json.MarshalIndent(listedReleases, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal releases: %w", err)
+		}
+		fmt.Println(string(data))
+
+		return nil
+	}
+
+	if listOutput == "wide" {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "NAME\tKUBERNETES VERSION\tHASH")
+		for i := range clusterStacks {
+			releaseAnnotations := annotations[clusterStacks[i].String()]
+			fmt.Fprintf(w, "%s\t%s\t%s\n", clusterStacks[i].String(), releaseAnnotations["kubernetesVersion"], releaseAnnotations["hash"])
+		}
+
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("failed to print releases: %w", err)
+		}
+
+		return nil
+	}
+
+	for i := range clusterStacks {
+		fmt.Println(clusterStacks[i].String())
+	}
+
+	return nil
+}
+
+// parseKubernetesVersionFilter parses Kubernetes versions like "1.27" or "v1.27.7".
+func parseKubernetesVersionFilter(str string) (kubernetesversion.KubernetesVersion, error) {
+	splitted := strings.Split(strings.TrimPrefix(str, "v"), ".")
+	if len(splitted) != 2 && len(splitted) != 3 {
+		return kubernetesversion.KubernetesVersion{}, kubernetesversion.ErrInvalidFormat
+	}
+
+	kubernetesVersion, err := kubernetesversion.New(splitted[0], splitted[1])
+	if err != nil {
+		return kubernetesversion.KubernetesVersion{}, fmt.Errorf("failed to parse kubernetes version: %w", err)
+	}
+
+	return kubernetesVersion, nil
+}
@@ -1,35 +0,0 @@
-/*
-Copyright 2024 The Kubernetes Authors.
-
-Licensed under the Apache License, Version 2.0 (the "License");
-you may not use this file except in compliance with the License.
-You may obtain a copy of the License at
-
-    http://www.apache.org/licenses/LICENSE-2.0
-
-Unless required by applicable law or agreed to in writing, software
-distributed under the License is distributed on an "AS IS" BASIS,
-WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
-See the License for the specific language governing permissions and
-limitations under the License.
-*/
-
-package cmd
-
-const (
-	clusterStackArtifactType = "application/vnd.scs.cluster-stacks.v1"
-
-	clusterAddonMediaType = "application/vnd.scs.cluster-addon.layer.v1.tar+gzip"
-
-	clusterClassMediaType = "application/vnd.scs.cluster-class.v1.tar+gzip"
-
-	clusterAddonConfigMediaType = "application/vnd.scs.cluster-addon.config.layer.v1+yaml"
-
-	metadataMediaType = "application/vnd.scs.metadata.layer.v1+yaml"
-
-	nodeImageMediaType = "application/vnd.scs.node-image.layer.v1.tar+gzip"
-
-	nodeImageConfigMediaType = "application/vnd.scs.node-image.config.layer.v1+yaml"
-
-	hashesMediaType = "application/vnd.scs.hashes.layer.v1+yaml"
-)
@@ -0,0 +1,96 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+
+	"github.com/SovereignCloudStack/csctl/pkg/clusterstack"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateAddonName string
+	migrateDryRun    bool
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrates a cluster stack from cluster-addon-values.yaml to clusteraddon.yaml",
+	Long: `It migrates a cluster stack of the old convention with cluster-addon-values.yaml to the
+	convention with clusteraddon.yaml. The cluster addon chart is moved to cluster-addon/<name>, the values of
+	cluster-addon-values.yaml are written to its overwrite.yaml and clusteraddon.yaml applies the chart after the
+	control plane is initialized and before each upgrade of the cluster. The changes are printed.`,
+	Example:      `csctl migrate tests/cluster-stacks/docker/ferrol --dry-run`,
+	RunE:         migrateAction,
+	SilenceUsage: true,
+}
+
+func init() {
+	migrateCmd.Flags().StringVar(&migrateAddonName, "addon-name", "cluster-addon", "Name of the directory in cluster-addon the chart is moved to. It is the name of the cluster addon in clusteraddon.yaml")
+	migrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "Print the changes without applying them")
+}
+
+func migrateAction(_ *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("please provide a valid command, migrate only accept one argument to path to the cluster stacks")
+	}
+	clusterStackPath := args[0]
+
+	changes, err := clusterstack.PlanMigration(clusterStackPath, migrateAddonName)
+	if err != nil {
+		return fmt.Errorf("failed to plan migration of %s: %w", clusterStackPath, err)
+	}
+
+	printMigrationChanges(changes)
+
+	if migrateDryRun {
+		return nil
+	}
+
+	if err := clusterstack.ApplyMigration(clusterStackPath, changes); err != nil {
+		return fmt.Errorf("failed to migrate %s: %w", clusterStackPath, err)
+	}
+
+	fmt.Printf("Migrated %s to clusteraddon.yaml\n", clusterStackPath)
+	return nil
+}
+
+// printMigrationChanges prints the changes like a diff. The content of created and deleted files is shown.
+func printMigrationChanges(changes []clusterstack.MigrationChange) {
+	for _, change := range changes {
+		switch change.Action {
+		case clusterstack.MigrationMove:
+			fmt.Printf("move %s -> %s\n", change.From, change.Path)
+		case clusterstack.MigrationCreate:
+			fmt.Printf("create %s\n", change.Path)
+			printPrefixedLines("+ ", change.Content)
+		case clusterstack.MigrationDelete:
+			fmt.Printf("delete %s\n", change.Path)
+			printPrefixedLines("- ", change.Content)
+		}
+	}
+}
+
+func printPrefixedLines(prefix string, data []byte) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fmt.Printf("%s%s\n", prefix, scanner.Text())
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SovereignCloudStack/csctl/pkg/logging"
+	"golang.org/x/term"
+)
+
+const (
+	// progressInterval is the minimum time between two updates of the progress bar of a file.
+	progressInterval = 200 * time.Millisecond
+	progressBarWidth = 30
+	progressNameLen  = 40
+)
+
+// progressBar renders the progress of transferred files on a terminal, one line per update.
+// Finished files keep their line, unfinished files overwrite each other.
+type progressBar struct {
+	mu      sync.Mutex
+	w       io.Writer
+	updated map[string]time.Time
+}
+
+// newProgressFunc returns the function that renders the progress of transfers to stderr.
+// It returns nil if no progress is shown, i.e. with --quiet, with json logs or if stderr is not a terminal.
+func newProgressFunc() func(name string, transferred, total int64) {
+	if quiet || logFormat != logging.FormatText || !term.IsTerminal(int(os.Stderr.Fd())) { // #nosec G115
+		return nil
+	}
+
+	bar := &progressBar{w: os.Stderr, updated: map[string]time.Time{}}
+	return bar.update
+}
+
+func (p *progressBar) update(name string, transferred, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	done := transferred >= total
+	now := time.Now()
+	if !done && now.Sub(p.updated[name]) < progressInterval {
+		return
+	}
+	p.updated[name] = now
+
+	percent := int64(100)
+	if total > 0 {
+		percent = transferred * 100 / total
+	}
+	filled := int(percent) * progressBarWidth / 100
+
+	label := name
+	if len(label) > progressNameLen {
+		label = "..." + label[len(label)-progressNameLen+3:]
+	}
+
+	// \x1b[K clears the rest of the line, which might contain a longer line of another file.
+	fmt.Fprintf(p.w, "\r\x1b[K%-*s [%s%s] %3d%% %s/%s", progressNameLen, label,
+		strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled), percent, formatBytes(transferred), formatBytes(total))
+	if done {
+		fmt.Fprintln(p.w)
+		delete(p.updated, name)
+	}
+}
+
+// formatBytes formats n bytes with a binary unit, e.g. 1.5 MiB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SovereignCloudStack/csctl/pkg/assetsclient/oci"
+	"github.com/spf13/cobra"
+)
+
+var (
+	promoteFrom      string
+	promoteTo        string
+	promoteOverwrite bool
+)
+
+var promoteCmd = &cobra.Command{
+	Use:   "promote",
+	Short: "Copies a cluster stack release from one OCI repository to another",
+	Long: `It copies an existing cluster stack release, e.g. from a staging repository
+	to a production repository. The credentials of the OCI registry are used for both repositories.`,
+	Example:      `csctl promote openstack-scs2-1-33-v3 --from oci://registry.example.com/staging --to oci://registry.example.com/prod`,
+	RunE:         promoteAction,
+	SilenceUsage: true,
+}
+
+func init() {
+	promoteCmd.Flags().StringVar(&promoteFrom, "from", "", "The OCI repository that contains the release. For example oci://registry.example.com/staging")
+	promoteCmd.Flags().StringVar(&promoteTo, "to", "", "The OCI repository the release is copied to. For example oci://registry.example.com/prod")
+	promoteCmd.Flags().BoolVar(&promoteOverwrite, "overwrite", false, "Overwrite the release if it already exists in the target repository")
+}
+
+func promoteAction(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("please provide a valid command, promote only accept one argument to the release tag")
+	}
+	releaseTag := args[0]
+
+	if promoteFrom == "" {
+		return fmt.Errorf("please specify the source repository with --from flag")
+	}
+	if promoteTo == "" {
+		return fmt.Errorf("please specify the target repository with --to flag")
+	}
+
+	sourceRepository := strings.TrimPrefix(promoteFrom, "oci://")
+	targetRepository := strings.TrimPrefix(promoteTo, "oci://")
+
+	sourceClient, err := oci.NewClientForRepository(sourceRepository, clientOptions)
+	if err != nil {
+		return fmt.Errorf("failed to create oci client for source repository %q: %w", sourceRepository, err)
+	}
+
+	found, err := sourceClient.FoundRelease(cmd.Context(), releaseTag)
+	if err != nil {
+		return fmt.Errorf("failed to check source repository %q: %w", sourceRepository, err)
+	}
+	if !found {
+		return fmt.Errorf("release %q not found in source repository %q", releaseTag, sourceRepository)
+	}
+
+	targetClient, err := oci.NewClientForRepository(targetRepository, clientOptions)
+	if err != nil {
+		return fmt.Errorf("failed to create oci client for target repository %q: %w", targetRepository, err)
+	}
+
+	found, err = targetClient.FoundRelease(cmd.Context(), releaseTag)
+	if err != nil {
+		return fmt.Errorf("failed to check target repository %q: %w", targetRepository, err)
+	}
+	if found && !promoteOverwrite {
+		return fmt.Errorf("release %q already exists in target repository %q, use --overwrite to replace it", releaseTag, targetRepository)
+	}
+
+	if err := sourceClient.CopyRelease(cmd.Context(), releaseTag, targetRepository, releaseTag); err != nil {
+		return fmt.Errorf("failed to promote release %q: %w", releaseTag, err)
+	}
+
+	fmt.Printf("Promoted release %s from %s to %s\n", releaseTag, sourceRepository, targetRepository)
+
+	return nil
+}
@@ -18,23 +18,87 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
 	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
 
+	"github.com/SovereignCloudStack/csctl/pkg/assetsclient/oci"
+	"github.com/SovereignCloudStack/csctl/pkg/hash"
+	"github.com/SovereignCloudStack/csctl/pkg/logging"
 	"github.com/spf13/cobra"
 )
 
+var (
+	logLevel  string
+	logFormat string
+	quiet     bool
+	noCache   bool
+	proxy     string
+	timeout   time.Duration
+	// clientOptions configures the clients of OCI registries. Its assetsclient.Options are used for GitHub and GitLab.
+	clientOptions oci.Options
+)
+
+var (
+	// timeoutCtx is the context with the deadline of --timeout. It is nil without timeout.
+	timeoutCtx    context.Context
+	cancelTimeout context.CancelFunc = func() {}
+	// currentStage describes what the command is doing. It is reported if the command times out.
+	currentStage atomic.Value
+)
+
 // rootCmd represents the base command when called without any subcommands.
 var rootCmd = &cobra.Command{
 	Use:   "csctl",
 	Short: "It is used to create cluster stack release.",
 	Long: `It is used building release artifacts using cluster stack template and
 by calculating latest GitHub release hash.`,
+	PersistentPreRunE: setup,
+	// Errors are printed by Execute, so that secrets are redacted.
+	SilenceErrors: true,
 }
 
+// ExitCodeNoChange is the exit code if the cluster stack did not change since the latest release, i.e. if a command
+// returns hash.ErrNoChange.
+const ExitCodeNoChange = 2
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// SIGINT and SIGTERM cancel the context of the command, so that temporary files and incomplete releases
+// are cleaned up. A second signal terminates csctl immediately.
 func Execute() {
-	err := rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := rootCmd.ExecuteContext(ctx)
+	cancelTimeout()
+	stop()
+
+	if err != nil && timeoutCtx != nil && errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+		if stage, ok := currentStage.Load().(string); ok {
+			err = fmt.Errorf("timed out after %s while %s: %w", timeout, stage, err)
+		} else {
+			err = fmt.Errorf("timed out after %s: %w", timeout, err)
+		}
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", logging.Redact(err.Error()))
+	}
+
+	if errors.Is(err, hash.ErrNoChange) {
+		os.Exit(ExitCodeNoChange)
+	}
 	if err != nil {
 		os.Exit(1)
 	}
@@ -42,5 +106,92 @@ func Execute() {
 
 func init() {
 	rootCmd.AddCommand(createCmd)
+	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(deleteCmd)
+	rootCmd.AddCommand(promoteCmd)
+	rootCmd.AddCommand(verifyCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(infoCmd)
 	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(cacheCmd)
+	rootCmd.AddCommand(cleanCmd)
+	rootCmd.AddCommand(migrateCmd)
+
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Log level. One of debug, info, warn or error")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Only print errors and the result of the command. Takes precedence over --log-level")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", logging.FormatText, "Log format. One of text or json. Logs are written to stderr")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Always download the latest release instead of using the cache in $XDG_CACHE_HOME/csctl")
+	rootCmd.PersistentFlags().BoolVar(&clientOptions.TLS.InsecureSkipVerify, "insecure-skip-tls-verify", false, "Skip the verification of the TLS certificate of OCI registries. Only use this for testing")
+	rootCmd.PersistentFlags().StringVar(&clientOptions.TLS.CACertFile, "ca-cert", "", "Path to a PEM file with CA certificates to trust for OCI registries in addition to the system ones")
+	rootCmd.PersistentFlags().IntVar(&clientOptions.Concurrency, "concurrency", oci.DefaultConcurrency, "Number of files that are pushed to or pulled from OCI registries at the same time. Lower it for rate-limited registries")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "Maximum duration of the command, e.g. 30m. Network requests and the provider plugin are aborted when it is exceeded. Zero means no timeout")
+	rootCmd.PersistentFlags().StringVar(&proxy, "proxy", "", "URL of the proxy for requests to OCI registries, GitHub and GitLab, e.g. http://proxy.example.com:3128. Defaults to HTTP_PROXY, HTTPS_PROXY and NO_PROXY")
+}
+
+// setup runs before every command.
+func setup(cmd *cobra.Command, args []string) error {
+	if err := setupLogger(cmd, args); err != nil {
+		return err
+	}
+
+	if clientOptions.Concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1, got %d", clientOptions.Concurrency)
+	}
+
+	clientOptions.Progress = newProgressFunc()
+
+	if timeout < 0 {
+		return fmt.Errorf("--timeout must not be negative, got %s", timeout)
+	}
+	if timeout > 0 {
+		timeoutCtx, cancelTimeout = context.WithTimeout(cmd.Context(), timeout)
+		cmd.SetContext(timeoutCtx)
+	}
+
+	return setupProxy()
+}
+
+// setStage sets the stage of the command that is reported if it times out, e.g. "publishing the release".
+func setStage(stage string) {
+	currentStage.Store(stage)
+}
+
+// setupProxy configures the proxy of the asset clients from --proxy.
+func setupProxy() error {
+	if proxy == "" {
+		return nil
+	}
+
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return fmt.Errorf("failed to parse --proxy: %w", err)
+	}
+	if proxyURL.Scheme == "" || proxyURL.Host == "" {
+		return fmt.Errorf("--proxy %q must be a URL like http://proxy.example.com:3128", proxy)
+	}
+
+	clientOptions.Proxy = proxyURL
+
+	return nil
+}
+
+// setupLogger creates the logger from the flags and puts it into the context of the command.
+// It is also the default logger, which is used by functions without a context.
+func setupLogger(cmd *cobra.Command, _ []string) error {
+	level := logLevel
+	if quiet {
+		level = "error"
+	}
+
+	logger, err := logging.New(os.Stderr, level, logFormat)
+	if err != nil {
+		return fmt.Errorf("failed to create logger: %w", err)
+	}
+
+	slog.SetDefault(logger)
+	cmd.SetContext(logging.IntoContext(cmd.Context(), logger))
+
+	return nil
 }
@@ -17,18 +17,39 @@ limitations under the License.
 package cmd
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"regexp"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 
 	csoclusterstack "github.com/SovereignCloudStack/cluster-stack-operator/pkg/clusterstack"
 	"github.com/SovereignCloudStack/cluster-stack-operator/pkg/version"
 	"github.com/SovereignCloudStack/csctl/pkg/assetsclient"
+	"github.com/SovereignCloudStack/csctl/pkg/cache"
 	"github.com/SovereignCloudStack/csctl/pkg/clusterstack"
+	"github.com/SovereignCloudStack/csctl/pkg/git"
+	"github.com/SovereignCloudStack/csctl/pkg/hash"
+	"github.com/SovereignCloudStack/csctl/pkg/logging"
+	"github.com/SovereignCloudStack/csctl/pkg/mediatype"
+	"golang.org/x/term"
 )
 
+// releaseAssetNames contains the files of a release that are needed to compute the next release.
+var releaseAssetNames = []string{
+	"metadata.yaml",
+	"hashes.json",
+	"cluster-addon-values.yaml",
+	"clusteraddon.yaml",
+	"node-images.yaml",
+}
+
 // getLatestReleaseFromRemoteRepository returns the latest release from the github repository.
 func getLatestReleaseFromRemoteRepository(ctx context.Context, mode string, config *clusterstack.CsctlConfig, ac assetsclient.Client) (string, error) {
 	ghReleases, err := ac.ListRelease(ctx)
@@ -36,16 +57,49 @@ func getLatestReleaseFromRemoteRepository(ctx context.Context, mode string, conf
 		return "", fmt.Errorf("failed to list releases on remote Git repository: %w", err)
 	}
 
+	return getLatestRelease(ctx, ghReleases, mode, config)
+}
+
+// getLatestRelease returns the latest of the releases that matches the mode and the cluster stack.
+// It returns the empty string if no release matches. Tags that are no release of the cluster stack are skipped, even
+// if they start like its releases, e.g. openstack-hosted-control-plane-1-34-v1 for the cluster stack hosted.
+func getLatestRelease(ctx context.Context, ghReleases []string, mode string, config *clusterstack.CsctlConfig) (string, error) {
 	var clusterStacks csoclusterstack.ClusterStacks
 
+	if _, err := config.ParseKubernetesVersion(); err != nil {
+		return "", fmt.Errorf("failed to parse kubernetes version: %w", err)
+	}
+
+	// The repository may hold releases of other cluster stacks, which might not even follow
+	// the release tag format. Only tags belonging to this cluster stack are validated strictly.
+	releasePrefix := fmt.Sprintf("%s-%s-", config.Config.Provider.Type, config.Config.ClusterStackName)
+
+	// The floating latest tag is not a release on its own.
+	alias, err := getLatestAlias(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to get latest alias: %w", err)
+	}
+
 	for _, ghRelease := range ghReleases {
+		if !strings.HasPrefix(ghRelease, releasePrefix) {
+			logging.FromContext(ctx).Debug("Skipping release tag of another cluster stack", "tag", ghRelease)
+			continue
+		}
+		if ghRelease == alias {
+			logging.FromContext(ctx).Debug("Skipping floating latest tag", "tag", ghRelease)
+			continue
+		}
+
 		clusterStackObject, matches, err := matchesSpec(ghRelease, mode, config)
 		if err != nil {
-			return "", fmt.Errorf("failed to get match release tag %q with spec of ClusterStack: %w", ghRelease, err)
+			logging.FromContext(ctx).Debug("Skipping release tag that is no release of the cluster stack", "tag", ghRelease, "reason", err.Error())
+			continue
 		}
 
 		if matches {
 			clusterStacks = append(clusterStacks, clusterStackObject)
+		} else {
+			logging.FromContext(ctx).Debug("Skipping release tag of another channel or kubernetes version", "tag", ghRelease)
 		}
 	}
 
@@ -53,12 +107,91 @@ func getLatestReleaseFromRemoteRepository(ctx context.Context, mode string, conf
 		return "", nil
 	}
 
-	sort.Sort(clusterStacks)
+	sortClusterStacks(clusterStacks)
 
 	str := clusterStacks.Latest().String()
 	return str, nil
 }
 
+// sortClusterStacks sorts cluster stacks like csoclusterstack.ClusterStacks, but compares numeric
+// patch versions of the alpha and beta channel as numbers, so that "v0-beta.10" is sorted after "v0-beta.9".
+func sortClusterStacks(clusterStacks csoclusterstack.ClusterStacks) {
+	sort.SliceStable(clusterStacks, func(i, j int) bool {
+		a, b := clusterStacks[i].Version, clusterStacks[j].Version
+		if a.Channel != b.Channel || a.Major != b.Major {
+			return clusterStacks.Less(i, j)
+		}
+
+		patchA, errA := strconv.Atoi(a.Patch)
+		patchB, errB := strconv.Atoi(b.Patch)
+		if errA != nil || errB != nil {
+			return clusterStacks.Less(i, j)
+		}
+
+		return patchA < patchB
+	})
+}
+
+// checkNewRelease returns an error if the new release already exists in the remote repository or if it is not
+// higher than the latest release of its channel, e.g. because the metadata of the latest release was edited manually.
+func checkNewRelease(releases []string, latestRelease, newRelease string) error {
+	if slices.Contains(releases, newRelease) {
+		return fmt.Errorf("release %s already exists", newRelease)
+	}
+
+	if latestRelease == "" {
+		return nil
+	}
+
+	latestClusterStack, err := csoclusterstack.NewFromClusterStackReleaseProperties(latestRelease)
+	if err != nil {
+		return fmt.Errorf("failed to parse latest release %q: %w", latestRelease, err)
+	}
+
+	newClusterStack, err := csoclusterstack.NewFromClusterStackReleaseProperties(newRelease)
+	if err != nil {
+		return fmt.Errorf("failed to parse new release %q: %w", newRelease, err)
+	}
+
+	clusterStacks := csoclusterstack.ClusterStacks{newClusterStack, latestClusterStack}
+	sortClusterStacks(clusterStacks)
+	if clusterStacks.Latest().String() != newClusterStack.String() {
+		return fmt.Errorf("release %s is not higher than the latest release %s", newRelease, latestRelease)
+	}
+
+	return nil
+}
+
+// getMajorVersion returns the major version of a cluster stack version like "v1-alpha.0" or
+// of a release tag like "docker-ferrol-1-27-v1". It returns 0 if the string is empty.
+func getMajorVersion(str string) (int, error) {
+	if str == "" {
+		return 0, nil
+	}
+
+	if v, err := version.New(str); err == nil {
+		return v.Major, nil
+	}
+
+	cs, err := csoclusterstack.NewFromClusterStackReleaseProperties(str)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %q: %w", str, err)
+	}
+
+	return cs.Version.Major, nil
+}
+
+// getInitialVersion returns the version of the first release of the mode. Stable releases start at v1. Releases of
+// the alpha and beta channel start at the major version of the latest stable release, or at v1 if there is none yet,
+// so that they never sort below a stable release.
+func getInitialVersion(mode string, latestStableMajor int) string {
+	if mode == stableMode {
+		return "v1"
+	}
+
+	return fmt.Sprintf("v%d-%s.0", max(latestStableMajor, 1), mode)
+}
+
 func matchesSpec(releaseTagName, mode string, cs *clusterstack.CsctlConfig) (csoclusterstack.ClusterStack, bool, error) {
 	csObject, err := csoclusterstack.NewFromClusterStackReleaseProperties(releaseTagName)
 	if err != nil {
@@ -70,14 +203,61 @@ func matchesSpec(releaseTagName, mode string, cs *clusterstack.CsctlConfig) (cso
 		return csoclusterstack.ClusterStack{}, false, fmt.Errorf("failed to parse kubernetes version %q: %w", cs.Config.ClusterStackName, err)
 	}
 
-	return csObject, csObject.Version.Channel == version.Channel(mode) &&
-		csObject.KubernetesVersion.StringWithDot() == kubernetesVersion.StringWithDot() &&
-		csObject.Name == cs.Config.ClusterStackName &&
-		csObject.Provider == cs.Config.Provider.Type, nil
+	filter := releaseFilter{
+		channel:           version.Channel(mode),
+		provider:          cs.Config.Provider.Type,
+		clusterStackName:  cs.Config.ClusterStackName,
+		kubernetesVersion: kubernetesVersion.StringWithDot(),
+	}
+
+	return csObject, filter.matches(csObject), nil
+}
+
+// releaseFilter filters cluster stack releases. Empty fields match every release.
+type releaseFilter struct {
+	channel           version.Channel
+	provider          string
+	clusterStackName  string
+	kubernetesVersion string
+}
+
+func (f releaseFilter) matches(cs csoclusterstack.ClusterStack) bool {
+	return (f.channel == "" || cs.Version.Channel == f.channel) &&
+		(f.kubernetesVersion == "" || cs.KubernetesVersion.StringWithDot() == f.kubernetesVersion) &&
+		(f.clusterStackName == "" || cs.Name == f.clusterStackName) &&
+		(f.provider == "" || cs.Provider == f.provider)
 }
 
 // downloadReleaseAssets downloads the specified release in the specified download path.
+// Releases are taken from the cache if the content of the release did not change, unless --no-cache is set.
 func downloadReleaseAssets(ctx context.Context, releaseTag, downloadPath string, ac assetsclient.Client) error {
+	var (
+		releaseCache *cache.Cache
+		releaseID    string
+	)
+
+	if identifier, ok := ac.(assetsclient.ReleaseIdentifier); ok && !noCache {
+		var err error
+		releaseCache, err = cache.New()
+		if err != nil {
+			return fmt.Errorf("failed to open cache: %w", err)
+		}
+
+		releaseID, err = identifier.ReleaseID(ctx, releaseTag)
+		if err != nil {
+			return fmt.Errorf("failed to identify release %s: %w", releaseTag, err)
+		}
+
+		found, err := releaseCache.Get(releaseID, downloadPath)
+		if err != nil {
+			return fmt.Errorf("failed to get release %s from cache: %w", releaseTag, err)
+		}
+		if found {
+			logging.FromContext(ctx).Info("Using cached release", "release", releaseTag, "id", releaseID)
+			return nil
+		}
+	}
+
 	if err := ac.DownloadReleaseAssets(ctx, releaseTag, downloadPath); err != nil {
 		// if download failed for some reason, delete the release directory so that it can be retried in the next reconciliation
 		if err := os.RemoveAll(downloadPath); err != nil {
@@ -86,37 +266,202 @@ func downloadReleaseAssets(ctx context.Context, releaseTag, downloadPath string,
 		return fmt.Errorf("failed to download release assets: %w", err)
 	}
 
+	if releaseCache != nil {
+		// A failure to fill the cache must not fail the command.
+		if err := releaseCache.Put(releaseID, downloadPath); err != nil {
+			logging.FromContext(ctx).Warn("Failed to cache release", "release", releaseTag, "error", err)
+		}
+	}
+
 	return nil
 }
 
-func getMediaType(fileName string) string {
-	if fileName == "clusteraddon.yaml" {
-		return clusterAddonConfigMediaType
+// mediaTypes maps the files of a release to their media types. It is overridden with --media-types.
+var mediaTypes = mediatype.Defaults()
+
+// getMediaType returns the media type of a file in the release directory.
+func getMediaType(fileName string) (string, error) {
+	return mediaTypes.ForFile(fileName)
+}
+
+// reservedAnnotations are the annotations that csctl sets on the published manifest.
+var reservedAnnotations = []string{"hash", "kubernetesVersion"}
+
+// annotationKeyRegex matches valid annotation keys, e.g. org.example.git-sha.
+var annotationKeyRegex = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._/-]*[A-Za-z0-9])?$`)
+
+// parseAnnotations parses annotations in the format key=value. Reserved annotations are only allowed with force.
+func parseAnnotations(flags []string, force bool) (map[string]string, error) {
+	annotations := make(map[string]string, len(flags))
+
+	for _, flag := range flags {
+		key, value, found := strings.Cut(flag, "=")
+		if !found {
+			return nil, fmt.Errorf("annotation %q is not in the format key=value", flag)
+		}
+
+		if !annotationKeyRegex.MatchString(key) {
+			return nil, fmt.Errorf("annotation key %q is invalid: it must consist of alphanumeric characters, '.', '_', '/' or '-' and start and end with an alphanumeric character", key)
+		}
+
+		if slices.Contains(reservedAnnotations, key) && !force {
+			return nil, fmt.Errorf("annotation %q is set by csctl, use --force to overwrite it", key)
+		}
+
+		if _, ok := annotations[key]; ok {
+			return nil, fmt.Errorf("annotation %q is specified more than once", key)
+		}
+
+		annotations[key] = value
 	}
 
-	if fileName == "metadata.yaml" {
-		return metadataMediaType
+	return annotations, nil
+}
+
+// parseVersionOverrides parses the versions of --set in the format path=version, e.g. versions.clusterStack=v3.
+// Paths and versions are validated, so that invalid overrides fail before anything is downloaded.
+func parseVersionOverrides(flags []string) (map[string]string, error) {
+	overrides := make(map[string]string, len(flags))
+
+	for _, flag := range flags {
+		path, value, found := strings.Cut(flag, "=")
+		if !found {
+			return nil, fmt.Errorf("%q is not in the format path=version", flag)
+		}
+
+		if err := clusterstack.SetVersion(&clusterstack.MetaData{}, path, value); err != nil {
+			return nil, err
+		}
+
+		if _, ok := overrides[path]; ok {
+			return nil, fmt.Errorf("version %q is specified more than once", path)
+		}
+
+		overrides[path] = value
 	}
 
-	if fileName == "node-images.yaml" {
-		return nodeImageConfigMediaType
+	return overrides, nil
+}
+
+// handleHashMode returns the metadata of the hash mode. With --git-hash, the short hash of the HEAD commit is used
+// instead of the hash of the cluster stack, unless the cluster stack is not in a git repository.
+func handleHashMode(ctx context.Context, clusterStackPath string, currentReleaseHash hash.ReleaseHash, kubernetesVersion string) (*clusterstack.MetaData, error) {
+	if !gitHash {
+		return clusterstack.HandleHashMode(currentReleaseHash, kubernetesVersion)
 	}
 
-	if fileName == "hashes.json" {
-		return hashesMediaType
+	commit, err := git.GetLatestGitCommit(clusterStackPath)
+	if err != nil {
+		if errors.Is(err, git.ErrNotInRepository) {
+			logging.FromContext(ctx).Warn("Cluster stack is not in a git repository. Using the hash of its content", "path", clusterStackPath)
+			return clusterstack.HandleHashMode(currentReleaseHash, kubernetesVersion)
+		}
+		return nil, fmt.Errorf("failed to get git commit: %w", err)
 	}
 
-	if strings.Contains(fileName, "cluster-addon") && strings.HasSuffix(fileName, ".tgz") {
-		return clusterAddonMediaType
+	logging.FromContext(ctx).Info("Using git commit for hash mode", "commit", commit)
+	return clusterstack.HandleGitHashMode(commit, kubernetesVersion)
+}
+
+// maxLoggedChanges is the number of uncommitted files that are logged by checkCleanWorktree.
+const maxLoggedChanges = 10
+
+// checkCleanWorktree warns if the cluster stack has uncommitted or untracked changes, because a release in hash mode
+// could not be reproduced from a clean checkout then. With --require-clean, it fails instead.
+func checkCleanWorktree(ctx context.Context, clusterStackPath string) error {
+	logger := logging.FromContext(ctx)
+
+	changes, err := git.UncommittedChanges(clusterStackPath)
+	if err != nil {
+		if errors.Is(err, git.ErrNotInRepository) {
+			if requireClean {
+				return fmt.Errorf("--require-clean needs a git repository: %w", err)
+			}
+			logger.Debug("Cluster stack is not in a git repository. Skipping check for uncommitted changes", "path", clusterStackPath)
+			return nil
+		}
+		return fmt.Errorf("failed to check for uncommitted changes: %w", err)
 	}
 
-	if strings.Contains(fileName, "cluster-class") && strings.HasSuffix(fileName, ".tgz") {
-		return clusterClassMediaType
+	if len(changes) == 0 {
+		return nil
 	}
 
-	if strings.Contains(fileName, "node-image") && strings.HasSuffix(fileName, ".tgz") {
-		return nodeImageMediaType
+	logged := changes
+	if len(logged) > maxLoggedChanges {
+		logged = logged[:maxLoggedChanges]
 	}
 
-	return ""
+	if requireClean {
+		return fmt.Errorf("cluster stack %s has %d uncommitted or untracked files, e.g. %s", clusterStackPath, len(changes), strings.Join(logged, ", "))
+	}
+
+	logger.Warn("Cluster stack has uncommitted or untracked files. The release cannot be reproduced from a clean checkout",
+		"path", clusterStackPath, "count", len(changes), "files", strings.Join(logged, ", "))
+
+	return nil
+}
+
+// nodeImageRegistrySchemes are the supported schemes of --node-image-registry.
+var nodeImageRegistrySchemes = []string{"oci", "s3"}
+
+// validateNodeImageRegistry checks that the node image registry is empty or a URL like oci://<host>/<path> or
+// s3://<bucket>/<path>, so that typos are found before the provider plugin is called.
+func validateNodeImageRegistry(registry string) error {
+	if registry == "" {
+		return nil
+	}
+
+	hint := "use the format oci://<host>/<path> or s3://<bucket>/<path>, e.g. oci://ghcr.io/foo/bar/node-images/staging/"
+
+	u, err := url.Parse(registry)
+	if err != nil {
+		return fmt.Errorf("node image registry %q is not a valid URL, %s: %w", registry, hint, err)
+	}
+
+	if !slices.Contains(nodeImageRegistrySchemes, u.Scheme) {
+		return fmt.Errorf("node image registry %q has unsupported scheme %q, %s", registry, u.Scheme, hint)
+	}
+
+	if u.Host == "" {
+		return fmt.Errorf("node image registry %q has no host, %s", registry, hint)
+	}
+
+	if strings.Trim(u.Path, "/") == "" {
+		return fmt.Errorf("node image registry %q has no path, %s", registry, hint)
+	}
+
+	return nil
+}
+
+// listReleaseAssets returns the files of the release directory with their media types.
+// Files with unknown media type, which are not published, have an empty media type.
+func listReleaseAssets(releaseDir string) ([]releaseAssetInfo, error) {
+	files, err := os.ReadDir(releaseDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", releaseDir, err)
+	}
+
+	assets := make([]releaseAssetInfo, 0, len(files))
+	for _, file := range files {
+		if !file.Type().IsRegular() {
+			continue
+		}
+
+		mediaType, err := getMediaType(file.Name())
+		if err != nil && !errors.Is(err, mediatype.ErrUnknown) {
+			return nil, err
+		}
+		assets = append(assets, releaseAssetInfo{Name: file.Name(), MediaType: mediaType})
+	}
+
+	return assets, nil
+}
+
+// stdinReader reads answers to prompts. It is shared, so that buffered input is not lost between prompts.
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// stdinIsTerminal returns true if stdin is a terminal, i.e. if a user can answer prompts.
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) // #nosec G115
 }
@@ -0,0 +1,149 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/SovereignCloudStack/csctl/pkg/clusterstack"
+)
+
+func TestGetLatestRelease(t *testing.T) {
+	config := &clusterstack.CsctlConfig{}
+	config.Config.KubernetesVersion = "v1.34.1"
+	config.Config.ClusterStackName = "hosted"
+	config.Config.Provider.Type = "openstack"
+
+	tests := []struct {
+		name     string
+		mode     string
+		releases []string
+		want     string
+	}{
+		{
+			name:     "no releases",
+			mode:     stableMode,
+			releases: nil,
+			want:     "",
+		},
+		{
+			name:     "only releases of a cluster stack with a colliding name",
+			mode:     stableMode,
+			releases: []string{"openstack-hosted-control-plane-1-34-v1", "openstack-hosted-control-plane-1-34-v2"},
+			want:     "",
+		},
+		{
+			name: "releases of a cluster stack with a colliding name are skipped",
+			mode: stableMode,
+			releases: []string{
+				"openstack-hosted-control-plane-1-34-v3",
+				"openstack-hosted-1-34-v1",
+				"openstack-hosted-1-34-v2",
+				"openstack-hosted-control-plane-1-34-v1",
+			},
+			want: "openstack-hosted-1-34-v2",
+		},
+		{
+			name: "other kubernetes versions, channels and the latest tag are skipped",
+			mode: stableMode,
+			releases: []string{
+				"openstack-hosted-1-34-v1",
+				"openstack-hosted-1-33-v5",
+				"openstack-hosted-1-34-v2-beta-0",
+				"openstack-hosted-1-34-latest",
+				"docker-hosted-1-34-v7",
+				"not-a-release",
+			},
+			want: "openstack-hosted-1-34-v1",
+		},
+		{
+			name: "beta channel",
+			mode: betaMode,
+			releases: []string{
+				"openstack-hosted-1-34-v1",
+				"openstack-hosted-1-34-v1-beta-9",
+				"openstack-hosted-1-34-v1-beta-10",
+				"openstack-hosted-control-plane-1-34-v1-beta-11",
+			},
+			want: "openstack-hosted-1-34-v1-beta-10",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getLatestRelease(context.Background(), tt.releases, tt.mode, config)
+			if err != nil {
+				t.Fatalf("getLatestRelease() failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("getLatestRelease() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateNodeImageRegistry(t *testing.T) {
+	tests := []struct {
+		registry string
+		wantErr  bool
+	}{
+		{registry: ""},
+		{registry: "oci://ghcr.io/foo/bar/node-images/staging/"},
+		{registry: "s3://bucket/node-images"},
+		{registry: "oci:/ghcr.io/foo/bar", wantErr: true},
+		{registry: "ghcr.io/foo/bar", wantErr: true},
+		{registry: "https://ghcr.io/foo/bar", wantErr: true},
+		{registry: "oci:///foo/bar", wantErr: true},
+		{registry: "oci://ghcr.io", wantErr: true},
+		{registry: "oci://ghcr.io/", wantErr: true},
+		{registry: "oci://ghcr.io:port/foo", wantErr: true},
+		{registry: "s3://bucket", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.registry, func(t *testing.T) {
+			err := validateNodeImageRegistry(tt.registry)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateNodeImageRegistry(%q) error = %v, wantErr %v", tt.registry, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetInitialVersion(t *testing.T) {
+	tests := []struct {
+		mode              string
+		latestStableMajor int
+		want              string
+	}{
+		{mode: stableMode, want: "v1"},
+		{mode: alphaMode, want: "v1-alpha.0"},
+		{mode: betaMode, want: "v1-beta.0"},
+		{mode: alphaMode, latestStableMajor: 3, want: "v3-alpha.0"},
+		{mode: betaMode, latestStableMajor: 2, want: "v2-beta.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/v%d", tt.mode, tt.latestStableMajor), func(t *testing.T) {
+			if got := getInitialVersion(tt.mode, tt.latestStableMajor); got != tt.want {
+				t.Errorf("getInitialVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
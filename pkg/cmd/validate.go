@@ -0,0 +1,96 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/SovereignCloudStack/csctl/pkg/clusterstack"
+	"github.com/SovereignCloudStack/csctl/pkg/providerplugin"
+	"github.com/spf13/cobra"
+)
+
+var validateCheckPlugin bool
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validates a cluster stack without building it",
+	Long: `It validates csctl.yaml and checks that the directories and files required to build
+	the cluster stack exist. All problems are reported at once, so it can be used as pre-commit hook.`,
+	Example:      `csctl validate tests/cluster-stacks/docker/ferrol --check-plugin`,
+	RunE:         validateAction,
+	SilenceUsage: true,
+}
+
+func init() {
+	validateCmd.Flags().BoolVar(&validateCheckPlugin, "check-plugin", false, "Check that the provider plugin is found, if the cluster stack needs one")
+}
+
+func validateAction(_ *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("please provide a valid command, validate only accept one argument to path to the cluster stacks")
+	}
+	clusterStackPath := args[0]
+
+	problems := validateClusterStack(clusterStackPath, validateCheckPlugin)
+
+	for _, problem := range problems {
+		fmt.Printf("%s: %v\n", clusterStackPath, problem)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("cluster stack %s is invalid: found %d problems", clusterStackPath, len(problems))
+	}
+
+	fmt.Printf("cluster stack %s is valid\n", clusterStackPath)
+	return nil
+}
+
+// validateClusterStack returns all problems found in the cluster stack.
+func validateClusterStack(clusterStackPath string, checkPlugin bool) []error {
+	var problems []error
+
+	config, err := clusterstack.ParseCsctlConfig(clusterStackPath)
+	if err != nil {
+		problems = append(problems, err)
+	} else {
+		problems = append(problems, unwrapJoined(config.Validate())...)
+
+		if checkPlugin {
+			if _, _, err := providerplugin.GetProviderExecutable(config, ""); err != nil {
+				problems = append(problems, err)
+			}
+		}
+	}
+
+	problems = append(problems, unwrapJoined(clusterstack.ValidateLayout(clusterStackPath))...)
+
+	return problems
+}
+
+// unwrapJoined returns the errors joined with errors.Join.
+func unwrapJoined(err error) []error {
+	if err == nil {
+		return nil
+	}
+
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+
+	return []error{err}
+}
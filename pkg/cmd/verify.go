@@ -0,0 +1,176 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	csoclusterstack "github.com/SovereignCloudStack/cluster-stack-operator/pkg/clusterstack"
+	"github.com/SovereignCloudStack/csctl/pkg/clusterstack"
+	"github.com/SovereignCloudStack/csctl/pkg/hash"
+	"github.com/spf13/cobra"
+)
+
+var verifyClusterStackPath string
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verifies that a local release directory is consistent",
+	Long: `It checks that hashes.json is valid and that the versions in metadata.yaml match the
+	name of the release directory and the packages in it. With --cluster-stack, the hashes of the
+	cluster stack are computed again and compared with hashes.json.`,
+	Example:      `csctl verify .release/docker-ferrol-1-27-v1 --cluster-stack tests/cluster-stacks/docker/ferrol`,
+	RunE:         verifyAction,
+	SilenceUsage: true,
+}
+
+func init() {
+	verifyCmd.Flags().StringVar(&verifyClusterStackPath, "cluster-stack", "", "Path to the cluster stack the release was created from. If set, its hashes are compared with hashes.json")
+}
+
+func verifyAction(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("please provide a valid command, verify only accept one argument to the release directory")
+	}
+	releaseDir := filepath.Clean(args[0])
+
+	problems, err := verifyRelease(cmd.Context(), releaseDir, verifyClusterStackPath)
+	if err != nil {
+		return fmt.Errorf("failed to verify release %s: %w", releaseDir, err)
+	}
+
+	for _, problem := range problems {
+		fmt.Printf("%s: %s\n", releaseDir, problem)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("release %s is inconsistent: found %d problems", releaseDir, len(problems))
+	}
+
+	fmt.Printf("release %s is consistent\n", releaseDir)
+	return nil
+}
+
+// verifyRelease returns the problems found in the release directory.
+func verifyRelease(ctx context.Context, releaseDir, clusterStackPath string) ([]string, error) {
+	var problems []string
+
+	releaseHash, err := hash.ParseReleaseHash(filepath.Join(releaseDir, "hashes.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hashes.json: %w", err)
+	}
+
+	if err := releaseHash.Validate(); err != nil {
+		problems = append(problems, fmt.Sprintf("invalid hashes.json: %v", err))
+	}
+
+	if clusterStackPath != "" {
+		currentHash, err := hash.GetHash(ctx, clusterStackPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get hash of cluster stack: %w", err)
+		}
+
+		problems = append(problems, compareReleaseHashes(releaseHash, currentHash)...)
+	}
+
+	metadata, err := clusterstack.ParseMetaData(releaseDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata.yaml: %w", err)
+	}
+
+	releaseTag := filepath.Base(releaseDir)
+	cs, err := csoclusterstack.NewFromClusterStackReleaseProperties(releaseTag)
+	if err != nil {
+		return append(problems, fmt.Sprintf("name of the release directory %q is not a cluster stack release: %v", releaseTag, err)), nil
+	}
+
+	if cs.Version.StringWithDot() != metadata.Versions.ClusterStack {
+		problems = append(problems, fmt.Sprintf("cluster stack version %s in metadata.yaml does not match the release %s", metadata.Versions.ClusterStack, releaseTag))
+	}
+
+	kubernetesVersion := strings.TrimPrefix(metadata.Versions.Kubernetes, "v")
+	if kubernetesVersion != cs.KubernetesVersion.StringWithDot() && !strings.HasPrefix(kubernetesVersion, cs.KubernetesVersion.StringWithDot()+".") {
+		problems = append(problems, fmt.Sprintf("kubernetes version %s in metadata.yaml does not match the release %s", metadata.Versions.Kubernetes, releaseTag))
+	}
+
+	prefix := fmt.Sprintf("%s-%s-%s", cs.Provider, cs.Name, cs.KubernetesVersion.String())
+
+	clusterClassPackage := fmt.Sprintf("%s-cluster-class-%s.tgz", prefix, metadata.Versions.ClusterStack)
+	if _, err := os.Stat(filepath.Join(releaseDir, clusterClassPackage)); err != nil {
+		problems = append(problems, fmt.Sprintf("cluster class package %s not found", clusterClassPackage))
+	}
+
+	files, err := os.ReadDir(releaseDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", releaseDir, err)
+	}
+
+	foundClusterAddon := false
+	for _, file := range files {
+		name := file.Name()
+		if !strings.Contains(name, "cluster-addon") || !(strings.HasSuffix(name, ".tgz") || strings.HasSuffix(name, ".tar.zst")) {
+			continue
+		}
+
+		version := strings.TrimSuffix(strings.TrimSuffix(name, ".tgz"), ".tar.zst")
+		if !strings.HasSuffix(version, "-"+metadata.Versions.Components.ClusterAddon) {
+			problems = append(problems, fmt.Sprintf("cluster addon package %s does not match cluster addon version %s in metadata.yaml", name, metadata.Versions.Components.ClusterAddon))
+		}
+		foundClusterAddon = true
+	}
+
+	if !foundClusterAddon {
+		problems = append(problems, "no cluster addon package found")
+	}
+
+	return problems, nil
+}
+
+// compareReleaseHashes returns the differences between the hashes of a release and the current hashes of the cluster stack.
+func compareReleaseHashes(releaseHash, currentHash hash.ReleaseHash) []string {
+	var problems []string
+
+	if err := currentHash.CheckVersion(releaseHash); err != nil {
+		return []string{fmt.Sprintf("cannot compare hashes.json with the cluster stack: %v", err)}
+	}
+
+	if releaseHash.ClusterStack != currentHash.ClusterStack {
+		problems = append(problems, fmt.Sprintf("cluster stack hash %s in hashes.json does not match the cluster stack hash %s", releaseHash.ClusterStack, currentHash.ClusterStack))
+	}
+
+	if releaseHash.ClusterClassDir != "" && releaseHash.ClusterClassDir != currentHash.ClusterClassDir {
+		problems = append(problems, fmt.Sprintf("cluster class hash %s in hashes.json does not match the cluster stack hash %s", releaseHash.ClusterClassDir, currentHash.ClusterClassDir))
+	}
+
+	if releaseHash.ClusterAddonDir != currentHash.ClusterAddonDir {
+		problems = append(problems, fmt.Sprintf("cluster addon hash %s in hashes.json does not match the cluster stack hash %s", releaseHash.ClusterAddonDir, currentHash.ClusterAddonDir))
+	}
+
+	if releaseHash.ClusterAddonValues != currentHash.ClusterAddonValues {
+		problems = append(problems, fmt.Sprintf("cluster addon values hash %s in hashes.json does not match the cluster stack hash %s", releaseHash.ClusterAddonValues, currentHash.ClusterAddonValues))
+	}
+
+	if releaseHash.NodeImageDir != currentHash.NodeImageDir {
+		problems = append(problems, fmt.Sprintf("node image hash %s in hashes.json does not match the cluster stack hash %s", releaseHash.NodeImageDir, currentHash.NodeImageDir))
+	}
+
+	return problems
+}
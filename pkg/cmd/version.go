@@ -17,8 +17,12 @@ limitations under the License.
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"runtime"
+	"runtime/debug"
 
+	"github.com/SovereignCloudStack/csctl/pkg/clusterstack"
 	"github.com/spf13/cobra"
 )
 
@@ -27,16 +31,95 @@ var (
 	Version = "dev"
 	// Commit against which csctl version is cut.
 	Commit = "unknown"
+	// BuildDate is the time at which csctl was built.
+	BuildDate = ""
 )
 
+var versionOutput string
+
+// versionInfo is the json representation of the version of csctl.
+type versionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate,omitempty"`
+	GoVersion string `json:"goVersion"`
+}
+
 var versionCmd = &cobra.Command{
 	Use:          "version",
 	Short:        "prints the latest version of csctl",
-	Run:          printVersion,
+	Example:      `csctl version -o json`,
+	RunE:         printVersion,
 	SilenceUsage: true,
 }
 
-func printVersion(_ *cobra.Command, _ []string) {
-	fmt.Println("csctl version:", Version)
-	fmt.Println("commit:", Commit)
+func init() {
+	versionCmd.Flags().StringVarP(&versionOutput, "output", "o", "", "Output format. One of '' or 'json'")
+}
+
+func printVersion(_ *cobra.Command, _ []string) error {
+	if versionOutput != "" && versionOutput != "json" {
+		return fmt.Errorf("output format %q is not supported please choose from - json", versionOutput)
+	}
+
+	buildInfo := getBuildInfo()
+	info := versionInfo{
+		Version:   buildInfo.CsctlVersion,
+		Commit:    buildInfo.GitCommit,
+		BuildDate: buildInfo.BuildDate,
+		GoVersion: runtime.Version(),
+	}
+
+	if versionOutput == "json" {
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal version: %w", err)
+		}
+		fmt.Println(string(data))
+
+		return nil
+	}
+
+	fmt.Println("csctl version:", info.Version)
+	fmt.Println("commit:", info.Commit)
+	if info.BuildDate != "" {
+		fmt.Println("build date:", info.BuildDate)
+	}
+	fmt.Println("go version:", info.GoVersion)
+
+	return nil
+}
+
+// getBuildInfo returns the version, commit and build date of csctl. They are set with ldflags at build time.
+// If they are not set, the build information embedded by the go toolchain is used.
+func getBuildInfo() clusterstack.BuildInfo {
+	buildInfo := clusterstack.BuildInfo{
+		CsctlVersion: Version,
+		GitCommit:    Commit,
+		BuildDate:    BuildDate,
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return buildInfo
+	}
+
+	if buildInfo.CsctlVersion == "dev" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		buildInfo.CsctlVersion = info.Main.Version
+	}
+
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if buildInfo.GitCommit == "unknown" {
+				buildInfo.GitCommit = setting.Value
+			}
+		case "vcs.time":
+			if buildInfo.BuildDate == "" {
+				buildInfo.BuildDate = setting.Value
+			}
+		}
+	}
+
+	return buildInfo
 }
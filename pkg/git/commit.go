@@ -18,15 +18,23 @@ limitations under the License.
 package git
 
 import (
+	"errors"
 	"fmt"
 
 	"gopkg.in/src-d/go-git.v4"
 )
 
-// GetLatestGitCommit returns latest git commit.
+// ErrNotInRepository is returned if a path is not inside a git repository.
+var ErrNotInRepository = errors.New("not in a git repository")
+
+// GetLatestGitCommit returns the short hash of the HEAD commit of the git repository that contains repoPath.
+// The repository is searched in repoPath and its parent directories.
 func GetLatestGitCommit(repoPath string) (string, error) {
-	repo, err := git.PlainOpen(repoPath)
+	repo, err := git.PlainOpenWithOptions(repoPath, &git.PlainOpenOptions{DetectDotGit: true})
 	if err != nil {
+		if errors.Is(err, git.ErrRepositoryNotExists) {
+			return "", fmt.Errorf("failed to open git repository of %s: %w", repoPath, ErrNotInRepository)
+		}
 		return "", fmt.Errorf("failed to open git repository: %w", err)
 	}
 
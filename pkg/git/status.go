@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/src-d/go-git.v4"
+)
+
+// UncommittedChanges returns the files in path that are modified, staged, deleted or untracked in the git
+// repository that contains path. Files ignored by .gitignore are not reported. The returned paths are relative
+// to the root of the repository.
+func UncommittedChanges(path string) ([]string, error) {
+	repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		if errors.Is(err, git.ErrRepositoryNotExists) {
+			return nil, fmt.Errorf("failed to open git repository of %s: %w", path, ErrNotInRepository)
+		}
+		return nil, fmt.Errorf("failed to open git repository: %w", err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree of git repository: %w", err)
+	}
+
+	status, err := worktree.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status of git worktree: %w", err)
+	}
+
+	prefix, err := relativeToRoot(worktree.Filesystem.Root(), path)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []string
+	for file, fileStatus := range status {
+		if fileStatus.Staging == git.Unmodified && fileStatus.Worktree == git.Unmodified {
+			continue
+		}
+		if prefix != "." && file != prefix && !strings.HasPrefix(file, prefix+"/") {
+			continue
+		}
+		changes = append(changes, file)
+	}
+	sort.Strings(changes)
+
+	return changes, nil
+}
+
+// relativeToRoot returns path relative to the root of the worktree with forward slashes, as used by git.
+func relativeToRoot(root, path string) (string, error) {
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve root of git worktree %s: %w", root, err)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("filepath.Abs(%q) failed: %w", path, err)
+	}
+	absPath, err = filepath.EvalSymlinks(absPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", absPath, err)
+	}
+
+	rel, err := filepath.Rel(root, absPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to get path of %s relative to %s: %w", absPath, root, err)
+	}
+
+	return filepath.ToSlash(rel), nil
+}
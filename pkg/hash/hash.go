@@ -18,30 +18,63 @@ limitations under the License.
 package hash
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/SovereignCloudStack/csctl/pkg/logging"
 	"golang.org/x/mod/sumdb/dirhash"
+	"golang.org/x/sync/errgroup"
+	"gopkg.in/src-d/go-git.v4/plumbing/format/gitignore"
 )
 
 const (
+	clusterClassDirName        = "cluster-class"
 	clusterAddonDirName        = "cluster-addon"
 	nodeImageDirName           = "node-image"
 	clusterAddonValuesFileName = "cluster-addon-values.yaml"
+	nodeImagesFileName         = "node-images.yaml"
+
+	// IgnoreFileName is the name of the file in the cluster stack directory that contains patterns
+	// in gitignore syntax of files that are excluded from all hashes.
+	IgnoreFileName = ".csctlignore"
+
+	clusterStackHashLength = 7
 )
 
+// CurrentVersion is the version of the algorithm with which GetHash computes the hashes. It has to be
+// increased whenever the hashes change for an unchanged cluster stack, e.g. because other files are hashed.
+// Hashes of different versions cannot be compared.
+const CurrentVersion = 1
+
+// ErrNoChange is returned if the cluster stack did not change since the latest release.
+var ErrNoChange = errors.New("no change in the cluster stack")
+
+// ErrVersionMismatch is returned if release hashes of different versions are compared.
+var ErrVersionMismatch = errors.New("hashes were computed with different versions of the hash algorithm")
+
 // ReleaseHash contains the information of release hash.
 type ReleaseHash struct {
-	ClusterStack       string `json:"clusterStack"`
+	// HashVersion is the version of the hash algorithm. It is missing in hashes.json of releases created before
+	// it was introduced, which is treated as version 1.
+	HashVersion  int    `json:"hashVersion,omitempty"`
+	ClusterStack string `json:"clusterStack"`
+	// ClusterClassDir is missing in hashes.json of releases created before it was introduced.
+	ClusterClassDir    string `json:"clusterClassDir,omitempty"`
 	ClusterAddonDir    string `json:"clusterAddonDir"`
 	ClusterAddonValues string `json:"clusterAddonValues"`
 	NodeImageDir       string `json:"nodeImageDir,omitempty"`
+	// NodeImages is the hash of node-images.yaml of the release, which is created by the provider plugin.
+	// It is only set if the release was created with --hash-node-images.
+	NodeImages string `json:"nodeImages,omitempty"`
 }
 
 // ParseReleaseHash parses the cluster-stack release hash.
@@ -60,58 +93,186 @@ func ParseReleaseHash(path string) (ReleaseHash, error) {
 }
 
 // GetHash returns the release hash.
-func GetHash(path string) (ReleaseHash, error) {
+func GetHash(ctx context.Context, path string) (ReleaseHash, error) {
 	entries, err := os.ReadDir(path)
 	if err != nil {
 		return ReleaseHash{}, fmt.Errorf("failed to read dir: %w", err)
 	}
 
-	releaseHash := ReleaseHash{}
+	releaseHash := ReleaseHash{HashVersion: CurrentVersion}
 
-	hash, err := dirhash.HashDir(path, "", dirhash.DefaultHash)
+	files, err := hashedFiles(ctx, path)
 	if err != nil {
-		return ReleaseHash{}, fmt.Errorf("failed to calculate cluster stack hash: %w", err)
+		return ReleaseHash{}, err
 	}
-	hash = clean(hash)
-	fmt.Printf("path %q: cluster stack hash: %q\n", path, hash)
 
-	releaseHash.ClusterStack = hash
+	// The hashes are independent of each other. Each goroutine writes only its own field of releaseHash,
+	// so the result is the same as computing them one after the other.
+	var g errgroup.Group
+
+	g.Go(func() error {
+		hash, err := hashFiles(path, "", files)
+		if err != nil {
+			return fmt.Errorf("failed to calculate cluster stack hash: %w", err)
+		}
+		releaseHash.ClusterStack = clean(hash)
+		logging.FromContext(ctx).Debug("Computed cluster stack hash", "path", path, "hash", releaseHash.ClusterStack)
+		return nil
+	})
 
 	for _, entry := range entries {
+		entry := entry
 		entryPath := filepath.Join(path, entry.Name())
-		if entry.IsDir() && (entry.Name() == clusterAddonDirName || entry.Name() == nodeImageDirName) {
-			hash, err := dirhash.HashDir(entryPath, "", dirhash.DefaultHash)
-			if err != nil {
-				return ReleaseHash{}, fmt.Errorf("failed to hash dir: %w", err)
-			}
-			hash = clean(hash)
 
+		if entry.IsDir() && (entry.Name() == clusterClassDirName || entry.Name() == clusterAddonDirName || entry.Name() == nodeImageDirName) {
+			var target *string
 			switch entry.Name() {
+			case clusterClassDirName:
+				target = &releaseHash.ClusterClassDir
 			case clusterAddonDirName:
-				releaseHash.ClusterAddonDir = hash
+				target = &releaseHash.ClusterAddonDir
 			case nodeImageDirName:
-				releaseHash.NodeImageDir = hash
+				target = &releaseHash.NodeImageDir
 			}
-		} else if !entry.IsDir() && entry.Name() == clusterAddonValuesFileName {
-			file, _ := os.Open(filepath.Clean(entryPath))
 
-			fileHash := sha256.New()
-			if _, err := io.Copy(fileHash, file); err != nil {
-				return ReleaseHash{}, fmt.Errorf("failed to copy dir: %w", err)
-			}
-			releaseHash.ClusterAddonValues = clean(base64.StdEncoding.EncodeToString(fileHash.Sum(nil)))
+			g.Go(func() error {
+				hash, err := hashFiles(path, entry.Name(), files)
+				if err != nil {
+					return fmt.Errorf("failed to hash dir: %w", err)
+				}
+				*target = clean(hash)
+				return nil
+			})
+		} else if !entry.IsDir() && entry.Name() == clusterAddonValuesFileName && slices.Contains(files, clusterAddonValuesFileName) {
+			g.Go(func() error {
+				hash, err := hashFile(entryPath)
+				if err != nil {
+					return err
+				}
+				releaseHash.ClusterAddonValues = clean(hash)
+				return nil
+			})
 		}
 	}
 
+	if err := g.Wait(); err != nil {
+		return ReleaseHash{}, err
+	}
+
 	return releaseHash, nil
 }
 
+// GetNodeImagesHash returns the hash of node-images.yaml in the release directory. If the release has no
+// node-images.yaml, e.g. because the cluster stack has no provider config, the hash is empty.
+func GetNodeImagesHash(releaseDir string) (string, error) {
+	path := filepath.Join(releaseDir, nodeImagesFileName)
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	hash, err := hashFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return clean(hash), nil
+}
+
+// hashFile returns the base64 encoded sha256 hash of the content of the file.
+func hashFile(path string) (string, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	fileHash := sha256.New()
+	if _, err := io.Copy(fileHash, file); err != nil {
+		return "", fmt.Errorf("failed to copy dir: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(fileHash.Sum(nil)), nil
+}
+
+// Validate checks that the release hash is complete and contains only hashes as written by GetHash.
+// It detects corrupted hashes.json files of downloaded releases.
+func (r ReleaseHash) Validate() error {
+	if r.ClusterStack == "" {
+		return fmt.Errorf("cluster stack hash is missing")
+	}
+
+	if r.ClusterAddonDir == "" {
+		return fmt.Errorf("cluster addon hash is missing")
+	}
+
+	if r.HashVersion < 0 {
+		return fmt.Errorf("hash version %d is invalid", r.HashVersion)
+	}
+
+	for name, hash := range map[string]string{
+		"cluster stack":        r.ClusterStack,
+		"cluster class":        r.ClusterClassDir,
+		"cluster addon":        r.ClusterAddonDir,
+		"cluster addon values": r.ClusterAddonValues,
+		"node image":           r.NodeImageDir,
+		"node images":          r.NodeImages,
+	} {
+		if strings.Trim(hash, "abcdefghijklmnopqrstuvwxyz0123456789") != "" {
+			return fmt.Errorf("%s hash %q contains invalid characters", name, hash)
+		}
+	}
+
+	return nil
+}
+
+// Version returns the version of the hash algorithm. Release hashes without version have version 1.
+func (r ReleaseHash) Version() int {
+	if r.HashVersion == 0 {
+		return 1
+	}
+	return r.HashVersion
+}
+
+// CheckVersion returns ErrVersionMismatch if the latest release hash was computed with another version of the
+// hash algorithm than r, so that the hashes cannot be compared.
+func (r ReleaseHash) CheckVersion(latestReleaseHash ReleaseHash) error {
+	if r.Version() != latestReleaseHash.Version() {
+		return fmt.Errorf("latest release has hash version %d, current hash version is %d: %w",
+			latestReleaseHash.Version(), r.Version(), ErrVersionMismatch)
+	}
+
+	return nil
+}
+
+// ClusterClassChanged returns true if the cluster class changed since the latest release. If the latest release hash
+// has no cluster class hash, because it was created before it was introduced, the cluster class is considered unchanged.
+func (r ReleaseHash) ClusterClassChanged(latestReleaseHash ReleaseHash) bool {
+	if latestReleaseHash.ClusterClassDir == "" {
+		return false
+	}
+	return r.ClusterClassDir != latestReleaseHash.ClusterClassDir
+}
+
+// NodeImageChanged returns true if the node image changed since the latest release. The node image changed if
+// the node-image directory changed or, if both release hashes contain the hash of node-images.yaml, node-images.yaml.
+func (r ReleaseHash) NodeImageChanged(latestReleaseHash ReleaseHash) bool {
+	if r.NodeImageDir != latestReleaseHash.NodeImageDir {
+		return true
+	}
+	return r.NodeImages != "" && latestReleaseHash.NodeImages != "" && r.NodeImages != latestReleaseHash.NodeImages
+}
+
 // ValidateWithLatestReleaseHash compare current hash with latest release hash.
+// It returns ErrNoChange if none of the components changed.
 func (r ReleaseHash) ValidateWithLatestReleaseHash(latestReleaseHash ReleaseHash) error {
-	if r.ClusterAddonDir == latestReleaseHash.ClusterAddonDir &&
+	if !r.ClusterClassChanged(latestReleaseHash) &&
+		r.ClusterAddonDir == latestReleaseHash.ClusterAddonDir &&
 		r.ClusterAddonValues == latestReleaseHash.ClusterAddonValues &&
-		r.NodeImageDir == latestReleaseHash.NodeImageDir {
-		return fmt.Errorf("no change in the cluster stack")
+		!r.NodeImageChanged(latestReleaseHash) {
+		return ErrNoChange
 	}
 
 	return nil
@@ -128,6 +289,102 @@ func clean(hash string) string {
 }
 
 // GetClusterStackHash returns the 7 character hash of the cluster stack content.
-func (r ReleaseHash) GetClusterStackHash() string {
-	return r.ClusterStack[:7]
+func (r ReleaseHash) GetClusterStackHash() (string, error) {
+	if len(r.ClusterStack) < clusterStackHashLength {
+		return "", fmt.Errorf("cluster stack hash %q is shorter than %d characters", r.ClusterStack, clusterStackHashLength)
+	}
+
+	return r.ClusterStack[:clusterStackHashLength], nil
+}
+
+// hashedFiles returns the files in the cluster stack directory that are hashed, relative to it and with forward slashes.
+// Files matching the patterns of the ignore file are excluded.
+func hashedFiles(ctx context.Context, path string) ([]string, error) {
+	matcher, err := readIgnoreFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	root := filepath.Clean(path)
+	if err := filepath.Walk(root, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if file == root {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return fmt.Errorf("failed to get path of %s relative to %s: %w", file, root, err)
+		}
+		rel = filepath.ToSlash(rel)
+
+		if matcher.Match(strings.Split(rel, "/"), info.IsDir()) {
+			logging.FromContext(ctx).Debug("Excluding file from hash", "path", rel)
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !info.IsDir() {
+			files = append(files, rel)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to walk cluster stack directory %s: %w", path, err)
+	}
+
+	return files, nil
+}
+
+// readIgnoreFile returns a matcher for the patterns of the ignore file in the cluster stack directory.
+// If there is no ignore file, the matcher matches no file.
+func readIgnoreFile(path string) (gitignore.Matcher, error) {
+	data, err := os.ReadFile(filepath.Join(filepath.Clean(path), IgnoreFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return gitignore.NewMatcher(nil), nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", IgnoreFileName, err)
+	}
+
+	var patterns []gitignore.Pattern
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+		patterns = append(patterns, gitignore.ParsePattern(line, nil))
+	}
+
+	return gitignore.NewMatcher(patterns), nil
+}
+
+// hashFiles computes the dirhash of the files in the subdirectory dir of the cluster stack directory, or of all
+// files if dir is empty. The file names are relative to dir, so that the hash is the same as that of dirhash.HashDir
+// if no file is excluded.
+func hashFiles(path, dir string, files []string) (string, error) {
+	var selected []string
+	for _, file := range files {
+		if dir == "" {
+			selected = append(selected, file)
+		} else if rel, ok := strings.CutPrefix(file, dir+"/"); ok {
+			selected = append(selected, rel)
+		}
+	}
+
+	dirPath := filepath.Join(path, dir)
+	open := func(name string) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(dirPath, filepath.FromSlash(name))) // #nosec G304
+	}
+
+	hash, err := dirhash.DefaultHash(selected, open)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash files of %s: %w", dirPath, err)
+	}
+
+	return hash, nil
 }
@@ -0,0 +1,224 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hash
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+func TestValidateWithLatestReleaseHash(t *testing.T) {
+	latest := ReleaseHash{
+		ClusterStack:       "stack",
+		ClusterClassDir:    "class",
+		ClusterAddonDir:    "addon",
+		ClusterAddonValues: "values",
+		NodeImageDir:       "nodeimagedir",
+		NodeImages:         "nodeimages",
+	}
+
+	tests := []struct {
+		name       string
+		change     func(current *ReleaseHash)
+		wantChange bool
+	}{
+		{
+			name:   "nothing changed",
+			change: func(*ReleaseHash) {},
+		},
+		{
+			name:       "only node-images.yaml changed",
+			change:     func(current *ReleaseHash) { current.NodeImages = "othernodeimages" },
+			wantChange: true,
+		},
+		{
+			name:   "node-images.yaml not hashed",
+			change: func(current *ReleaseHash) { current.NodeImages = "" },
+		},
+		{
+			name:       "only node-image directory changed",
+			change:     func(current *ReleaseHash) { current.NodeImageDir = "othernodeimagedir" },
+			wantChange: true,
+		},
+		{
+			name: "only node images changed",
+			change: func(current *ReleaseHash) {
+				current.NodeImageDir = "othernodeimagedir"
+				current.NodeImages = "othernodeimages"
+			},
+			wantChange: true,
+		},
+		{
+			name:       "only cluster class changed",
+			change:     func(current *ReleaseHash) { current.ClusterClassDir = "otherclass" },
+			wantChange: true,
+		},
+		{
+			name:       "only cluster addon changed",
+			change:     func(current *ReleaseHash) { current.ClusterAddonDir = "otheraddon" },
+			wantChange: true,
+		},
+		{
+			name:       "only cluster addon values changed",
+			change:     func(current *ReleaseHash) { current.ClusterAddonValues = "othervalues" },
+			wantChange: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := latest
+			tt.change(&current)
+
+			err := current.ValidateWithLatestReleaseHash(latest)
+			if tt.wantChange && err != nil {
+				t.Errorf("ValidateWithLatestReleaseHash() = %v, want nil", err)
+			}
+			if !tt.wantChange && !errors.Is(err, ErrNoChange) {
+				t.Errorf("ValidateWithLatestReleaseHash() = %v, want ErrNoChange", err)
+			}
+		})
+	}
+}
+
+func TestGetClusterStackHash(t *testing.T) {
+	tests := []struct {
+		clusterStack string
+		want         string
+		wantErr      bool
+	}{
+		{clusterStack: "", wantErr: true},
+		{clusterStack: "abc", wantErr: true},
+		{clusterStack: "abcdefg", want: "abcdefg"},
+		{clusterStack: "abcdefghijk", want: "abcdefg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.clusterStack, func(t *testing.T) {
+			got, err := ReleaseHash{ClusterStack: tt.clusterStack}.GetClusterStackHash()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// serialHash computes the release hash one after the other with dirhash.HashDir, like GetHash did before it
+// computed the hashes concurrently. It only supports cluster stacks without ignore file.
+func serialHash(t *testing.T, path string) ReleaseHash {
+	t.Helper()
+
+	hashDir := func(dir string) string {
+		hash, err := dirhash.HashDir(filepath.Join(path, dir), "", dirhash.DefaultHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return clean(hash)
+	}
+
+	releaseHash := ReleaseHash{HashVersion: CurrentVersion, ClusterStack: hashDir("")}
+
+	for dir, target := range map[string]*string{
+		clusterClassDirName: &releaseHash.ClusterClassDir,
+		clusterAddonDirName: &releaseHash.ClusterAddonDir,
+		nodeImageDirName:    &releaseHash.NodeImageDir,
+	} {
+		if _, err := os.Stat(filepath.Join(path, dir)); err == nil {
+			*target = hashDir(dir)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(path, clusterAddonValuesFileName)); err == nil {
+		hash, err := hashFile(filepath.Join(path, clusterAddonValuesFileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		releaseHash.ClusterAddonValues = clean(hash)
+	}
+
+	return releaseHash
+}
+
+func TestGetHashMatchesSerial(t *testing.T) {
+	for _, name := range []string{"ferrol", "valencia"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join("..", "..", "tests", "cluster-stacks", "docker", name)
+
+			releaseHash, err := GetHash(context.Background(), path)
+			if err != nil {
+				t.Fatalf("GetHash() failed: %v", err)
+			}
+
+			got, err := json.Marshal(releaseHash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := json.Marshal(serialHash(t, path))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(got, want) {
+				t.Errorf("GetHash() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func BenchmarkGetHash(b *testing.B) {
+	path := b.TempDir()
+	data := bytes.Repeat([]byte("cluster stack\n"), 4096)
+
+	for _, dir := range []string{clusterClassDirName, clusterAddonDirName, nodeImageDirName} {
+		for i := 0; i < 100; i++ {
+			file := filepath.Join(path, dir, fmt.Sprintf("dir-%d", i%10), fmt.Sprintf("file-%d.yaml", i))
+			if err := os.MkdirAll(filepath.Dir(file), 0o750); err != nil {
+				b.Fatal(err)
+			}
+			if err := os.WriteFile(file, data, 0o600); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	if err := os.WriteFile(filepath.Join(path, clusterAddonValuesFileName), data, 0o600); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := GetHash(context.Background(), path); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package logging provides the logger of csctl.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	// FormatText is a human-readable log format. It is the default.
+	FormatText = "text"
+	// FormatJSON logs one JSON object per line.
+	FormatJSON = "json"
+)
+
+type contextKey struct{}
+
+// New returns a logger that writes to w. Level is one of debug, info, warn or error.
+// Format is one of FormatText or FormatJSON. Secrets registered with AddSecret are redacted.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var logLevel slog.Level
+	if err := logLevel.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q: %w", level, err)
+	}
+
+	switch format {
+	case FormatText:
+		return slog.New(&textHandler{w: w, mu: &sync.Mutex{}, level: logLevel}), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: logLevel, ReplaceAttr: redactAttr})), nil
+	default:
+		return nil, fmt.Errorf("log format %q is not supported please choose from - %s or %s", format, FormatText, FormatJSON)
+	}
+}
+
+// IntoContext returns a copy of ctx that carries logger.
+func IntoContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger of ctx. If ctx has no logger, the default logger is returned.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// ProgressWriter returns the writer for progress output of external tools, like provider plugins.
+// It is stderr if the logger logs informational messages, otherwise nil. For os/exec, nil discards the output
+// without a pipe, which a child of a killed command could keep open.
+func ProgressWriter(ctx context.Context, logger *slog.Logger) io.Writer {
+	if logger.Enabled(ctx, slog.LevelInfo) {
+		return os.Stderr
+	}
+	return nil
+}
+
+// textHandler writes the message followed by the attributes as key=value, without time and level.
+// Warnings and errors are prefixed, so that they stand out.
+// Groups are not supported, their attributes are written without prefix.
+type textHandler struct {
+	w     io.Writer
+	mu    *sync.Mutex
+	level slog.Level
+	attrs []slog.Attr
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *textHandler) Handle(_ context.Context, record slog.Record) error {
+	var b strings.Builder
+
+	switch {
+	case record.Level >= slog.LevelError:
+		b.WriteString("Error: ")
+	case record.Level >= slog.LevelWarn:
+		b.WriteString("Warning: ")
+	}
+	b.WriteString(record.Message)
+
+	writeAttr := func(attr slog.Attr) bool {
+		fmt.Fprintf(&b, " %s=%q", attr.Key, attr.Value.String())
+		return true
+	}
+	for _, attr := range h.attrs {
+		writeAttr(attr)
+	}
+	record.Attrs(writeAttr)
+	b.WriteString("\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, err := io.WriteString(h.w, Redact(b.String())); err != nil {
+		return fmt.Errorf("failed to write log: %w", err)
+	}
+	return nil
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &textHandler{
+		w:     h.w,
+		mu:    h.mu,
+		level: h.level,
+		attrs: append(append([]slog.Attr{}, h.attrs...), attrs...),
+	}
+}
+
+func (h *textHandler) WithGroup(_ string) slog.Handler {
+	return h
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// redacted replaces secrets in logs and errors.
+const redacted = "***"
+
+// minSecretLength is the minimum length of secrets that are redacted. Shorter values would
+// garble the output, as they are likely to be part of other words.
+const minSecretLength = 4
+
+var (
+	secretsMu sync.RWMutex
+	secrets   []string
+)
+
+// AddSecret registers secret values, like passwords and tokens, that are replaced with *** in logs and
+// by Redact and RedactError. Empty and very short values are ignored.
+func AddSecret(values ...string) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+
+	for _, value := range values {
+		if len(value) < minSecretLength {
+			continue
+		}
+		secrets = append(secrets, value)
+	}
+}
+
+// Redact replaces all registered secrets in s with ***.
+func Redact(s string) string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+
+	return s
+}
+
+// RedactError returns err with all registered secrets in its message replaced with ***.
+// The returned error wraps err, so that errors.Is and errors.As still work.
+func RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	message := Redact(err.Error())
+	if message == err.Error() {
+		return err
+	}
+
+	return &redactedError{message: message, err: err}
+}
+
+type redactedError struct {
+	message string
+	err     error
+}
+
+func (e *redactedError) Error() string {
+	return e.message
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// redactAttr replaces secrets in string and error values of log attributes.
+func redactAttr(_ []string, attr slog.Attr) slog.Attr {
+	switch value := attr.Value.Resolve(); {
+	case value.Kind() == slog.KindString:
+		attr.Value = slog.StringValue(Redact(value.String()))
+	case value.Kind() == slog.KindAny:
+		if err, ok := value.Any().(error); ok {
+			attr.Value = slog.StringValue(Redact(err.Error()))
+		}
+	}
+
+	return attr
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	const password = "s3cr3t-password"
+	AddSecret(password, "abc")
+
+	for _, format := range []string{FormatText, FormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			var out bytes.Buffer
+			logger, err := New(&out, "debug", format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			logger.With("auth", "user:"+password).Info("Logging in with "+password,
+				"password", password,
+				"error", fmt.Errorf("failed to log in with %s", password),
+			)
+
+			if strings.Contains(out.String(), password) {
+				t.Errorf("log contains the password: %s", out.String())
+			}
+			if !strings.Contains(out.String(), redacted) {
+				t.Errorf("log does not contain %s: %s", redacted, out.String())
+			}
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		errUnauthorized := errors.New("unauthorized")
+		err := RedactError(fmt.Errorf("failed to log in with %s: %w", password, errUnauthorized))
+
+		if strings.Contains(err.Error(), password) {
+			t.Errorf("error contains the password: %v", err)
+		}
+		if !errors.Is(err, errUnauthorized) {
+			t.Error("redacted error does not wrap the original error")
+		}
+	})
+
+	t.Run("short secrets are ignored", func(t *testing.T) {
+		if got := Redact("abcdef"); got != "abcdef" {
+			t.Errorf("Redact() = %q, want it unchanged", got)
+		}
+	})
+}
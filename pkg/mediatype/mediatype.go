@@ -0,0 +1,236 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package mediatype contains the media types of the files of a cluster stack release.
+// The media types can be overridden with a mapping file for tools that expect other media types.
+package mediatype
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ArtifactType is the default artifact type of the OCI manifest of a release.
+	ArtifactType = "application/vnd.scs.cluster-stacks.v1"
+
+	// ClusterAddon is the media type of the cluster addon package compressed with gzip.
+	ClusterAddon = "application/vnd.scs.cluster-addon.layer.v1.tar+gzip"
+
+	// ClusterAddonZstd is the media type of the cluster addon package compressed with zstd.
+	ClusterAddonZstd = "application/vnd.scs.cluster-addon.layer.v1.tar+zstd"
+
+	// ClusterClass is the media type of the cluster class package.
+	ClusterClass = "application/vnd.scs.cluster-class.v1.tar+gzip"
+
+	// ClusterAddonValues is the media type of cluster-addon-values.yaml.
+	ClusterAddonValues = "application/vnd.scs.cluster-addon.values.layer.v1+yaml"
+
+	// ClusterAddonConfig is the media type of clusteraddon.yaml.
+	ClusterAddonConfig = "application/vnd.scs.cluster-addon.config.layer.v1+yaml"
+
+	// Metadata is the media type of metadata.yaml.
+	Metadata = "application/vnd.scs.metadata.layer.v1+yaml"
+
+	// NodeImage is the media type of node image packages compressed with gzip.
+	NodeImage = "application/vnd.scs.node-image.layer.v1.tar+gzip"
+
+	// NodeImageZstd is the media type of node image packages compressed with zstd.
+	NodeImageZstd = "application/vnd.scs.node-image.layer.v1.tar+zstd"
+
+	// NodeImageConfig is the media type of node-images.yaml.
+	NodeImageConfig = "application/vnd.scs.node-image.config.layer.v1+yaml"
+
+	// Hashes is the media type of hashes.json.
+	Hashes = "application/vnd.scs.hashes.layer.v1+yaml"
+
+	// HelmProvenance is the media type helm uses for provenance files in OCI registries.
+	HelmProvenance = "application/vnd.cncf.helm.chart.provenance.v1.prov"
+)
+
+// Kind is a kind of file of a release. It is the key of the mapping file.
+type Kind string
+
+const (
+	// KindClusterAddon is the cluster addon package compressed with gzip.
+	KindClusterAddon Kind = "clusterAddon"
+	// KindClusterAddonZstd is the cluster addon package compressed with zstd.
+	KindClusterAddonZstd Kind = "clusterAddonZstd"
+	// KindClusterClass is the cluster class package.
+	KindClusterClass Kind = "clusterClass"
+	// KindClusterAddonValues is cluster-addon-values.yaml.
+	KindClusterAddonValues Kind = "clusterAddonValues"
+	// KindClusterAddonConfig is clusteraddon.yaml.
+	KindClusterAddonConfig Kind = "clusterAddonConfig"
+	// KindMetadata is metadata.yaml.
+	KindMetadata Kind = "metadata"
+	// KindNodeImage is a node image package compressed with gzip.
+	KindNodeImage Kind = "nodeImage"
+	// KindNodeImageZstd is a node image package compressed with zstd.
+	KindNodeImageZstd Kind = "nodeImageZstd"
+	// KindNodeImageConfig is node-images.yaml.
+	KindNodeImageConfig Kind = "nodeImageConfig"
+	// KindHashes is hashes.json.
+	KindHashes Kind = "hashes"
+	// KindHelmProvenance is the provenance file of a signed helm package.
+	KindHelmProvenance Kind = "helmProvenance"
+)
+
+// ErrUnknown is returned for files that are not part of a cluster stack release.
+var ErrUnknown = errors.New("unknown media type")
+
+// mediaTypeRegex matches media types as defined by RFC 6838, which the OCI image spec uses for artifact types.
+var mediaTypeRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9!#$&^_.+-]{0,126}/[A-Za-z0-9][A-Za-z0-9!#$&^_.+-]{0,126}$`)
+
+// Validate checks that mediaType is a media type like application/vnd.scs.cluster-stacks.v1.
+func Validate(mediaType string) error {
+	if !mediaTypeRegex.MatchString(mediaType) {
+		return fmt.Errorf("%q is not a valid media type, expected <type>/<subtype> like %s", mediaType, ArtifactType)
+	}
+
+	return nil
+}
+
+// Mapping maps the kinds of files of a release to their media types.
+type Mapping map[Kind]string
+
+// Defaults returns the mapping with the default media types.
+func Defaults() Mapping {
+	return Mapping{
+		KindClusterAddon:       ClusterAddon,
+		KindClusterAddonZstd:   ClusterAddonZstd,
+		KindClusterClass:       ClusterClass,
+		KindClusterAddonValues: ClusterAddonValues,
+		KindClusterAddonConfig: ClusterAddonConfig,
+		KindMetadata:           Metadata,
+		KindNodeImage:          NodeImage,
+		KindNodeImageZstd:      NodeImageZstd,
+		KindNodeImageConfig:    NodeImageConfig,
+		KindHashes:             Hashes,
+		KindHelmProvenance:     HelmProvenance,
+	}
+}
+
+// ReadMapping reads a YAML file that maps kinds to media types, e.g. "metadata: application/yaml".
+// Kinds that are not in the file keep their default media type.
+func ReadMapping(path string) (Mapping, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read media type file %s: %w", path, err)
+	}
+
+	overrides := map[Kind]string{}
+	if err := yaml.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal media type file %s: %w", path, err)
+	}
+
+	mapping := Defaults()
+	for kind, mediaType := range overrides {
+		if _, ok := mapping[kind]; !ok {
+			return nil, fmt.Errorf("unknown kind %q in media type file %s, expected one of: %s", kind, path, strings.Join(mapping.kinds(), ", "))
+		}
+		if err := Validate(mediaType); err != nil {
+			return nil, fmt.Errorf("invalid media type of %q in media type file %s: %w", kind, path, err)
+		}
+		mapping[kind] = mediaType
+	}
+
+	return mapping, nil
+}
+
+// ForFile returns the media type of a file in the release directory.
+// It returns ErrUnknown if the file is not part of a cluster stack release.
+func (m Mapping) ForFile(fileName string) (string, error) {
+	kind, err := KindOf(fileName)
+	if err != nil {
+		return "", err
+	}
+
+	return m[kind], nil
+}
+
+func (m Mapping) kinds() []string {
+	kinds := make([]string, 0, len(m))
+	for kind := range m {
+		kinds = append(kinds, string(kind))
+	}
+	sort.Strings(kinds)
+
+	return kinds
+}
+
+// KindOf returns the kind of a file in the release directory.
+// It returns ErrUnknown if the file is not part of a cluster stack release.
+func KindOf(fileName string) (Kind, error) {
+	if fileName == "clusteraddon.yaml" {
+		return KindClusterAddonConfig, nil
+	}
+
+	if fileName == "metadata.yaml" {
+		return KindMetadata, nil
+	}
+
+	if fileName == "node-images.yaml" {
+		return KindNodeImageConfig, nil
+	}
+
+	if fileName == "hashes.json" {
+		return KindHashes, nil
+	}
+
+	if fileName == "cluster-addon-values.yaml" {
+		return KindClusterAddonValues, nil
+	}
+
+	if strings.HasSuffix(fileName, ".tgz.prov") {
+		return KindHelmProvenance, nil
+	}
+
+	if strings.Contains(fileName, "cluster-addon") && strings.HasSuffix(fileName, ".tgz") {
+		return KindClusterAddon, nil
+	}
+
+	if strings.Contains(fileName, "cluster-addon") && strings.HasSuffix(fileName, ".tar.zst") {
+		return KindClusterAddonZstd, nil
+	}
+
+	if strings.Contains(fileName, "cluster-class") && strings.HasSuffix(fileName, ".tgz") {
+		return KindClusterClass, nil
+	}
+
+	if strings.Contains(fileName, "node-image") && strings.HasSuffix(fileName, ".tgz") {
+		return KindNodeImage, nil
+	}
+
+	if strings.Contains(fileName, "node-image") && strings.HasSuffix(fileName, ".tar.zst") {
+		return KindNodeImageZstd, nil
+	}
+
+	// With the old convention, a cluster addon that is split into one chart per subdirectory is packaged by helm as
+	// <chart>-<version>.tgz for each chart. The other helm packages of a release are recognized by their names above.
+	if strings.HasSuffix(fileName, ".tgz") {
+		return KindClusterAddon, nil
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrUnknown, fileName)
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mediatype
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		fileName string
+		want     Kind
+		wantErr  bool
+	}{
+		{fileName: "metadata.yaml", want: KindMetadata},
+		{fileName: "hashes.json", want: KindHashes},
+		{fileName: "docker-ferrol-1-27-cluster-class-v1.tgz", want: KindClusterClass},
+		{fileName: "docker-ferrol-1-27-cluster-class-v1.tgz.prov", want: KindHelmProvenance},
+		{fileName: "docker-ferrol-1-27-cluster-addon-v1.tgz", want: KindClusterAddon},
+		{fileName: "docker-valencia-1-27-cluster-addon-v1.tar.zst", want: KindClusterAddonZstd},
+		{fileName: "docker-ferrol-1-27-node-image-v1.tgz", want: KindNodeImage},
+		// A chart of a cluster addon that is split into subdirectories with the old convention.
+		{fileName: "metrics-server-v2.tgz", want: KindClusterAddon},
+		{fileName: "notes.txt", wantErr: true},
+		{fileName: "sbom.tar.zst", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			got, err := KindOf(tt.fileName)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnknown) {
+					t.Fatalf("KindOf() error = %v, want %v", err, ErrUnknown)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("KindOf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
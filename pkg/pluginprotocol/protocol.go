@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package pluginprotocol defines the contract between csctl and provider plugins.
+// Plugin authors can import it to implement the protocol.
+//
+// A plugin is an executable called csctl-<provider> that supports the following subcommands:
+//
+//	csctl-<provider> version
+//	csctl-<provider> create-node-images <cluster-stack-path> <cluster-stack-release-dir> <node-image-registry> <protocol-version>
+//
+// "version" writes a VersionResponse as JSON to stdout. csctl calls it before any other subcommand
+// and aborts if the protocol version of the plugin differs from its own.
+//
+// "create-node-images" creates the node images. Additionally to the positional arguments, which must
+// not be reordered to stay compatible with existing plugins, csctl writes the Envelope as JSON to stdin.
+package pluginprotocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/SovereignCloudStack/csctl/pkg/clusterstack"
+)
+
+// ProtocolVersion is the version of the protocol between csctl and the provider plugins.
+// It is passed as an additional argument after the positional arguments. Plugins that know
+// this version read the Envelope from stdin instead of parsing csctl.yaml on their own.
+const ProtocolVersion = "1"
+
+const (
+	// CommandVersion is the subcommand that returns the protocol version of the plugin.
+	CommandVersion = "version"
+
+	// CommandCreateNodeImages is the subcommand that creates the node images.
+	CommandCreateNodeImages = "create-node-images"
+)
+
+// VersionResponse is written by plugins as JSON to stdout for the version subcommand.
+type VersionResponse struct {
+	ProtocolVersion string `json:"protocolVersion"`
+}
+
+// Envelope contains all information a provider plugin needs to create node images.
+// It is written as JSON to the stdin of the plugin.
+type Envelope struct {
+	ClusterStackPath  string `json:"clusterStackPath"`
+	ReleaseDir        string `json:"releaseDir"`
+	NodeImageRegistry string `json:"nodeImageRegistry"`
+	// ProviderAPIVersion is the apiVersion of the provider config in csctl.yaml, e.g.
+	// docker.csctl.clusterstack.x-k8s.io/v1alpha1. It is empty if csctl.yaml does not set it.
+	ProviderAPIVersion string                    `json:"providerAPIVersion,omitempty"`
+	Config             *clusterstack.CsctlConfig `json:"config"`
+	Metadata           *clusterstack.MetaData    `json:"metadata"`
+}
+
+// CheckProviderAPIVersion returns an error if the apiVersion of the provider config is not one of the supported
+// versions, so that plugins can reject provider configs they don't understand. An empty apiVersion is accepted.
+func (e *Envelope) CheckProviderAPIVersion(supported ...string) error {
+	if e.ProviderAPIVersion == "" || slices.Contains(supported, e.ProviderAPIVersion) {
+		return nil
+	}
+
+	return fmt.Errorf("provider apiVersion %q is not supported, supported versions are: %s",
+		e.ProviderAPIVersion, strings.Join(supported, ", "))
+}
+
+// ReadEnvelope reads the envelope written by csctl. It is meant to be used by provider plugins.
+func ReadEnvelope(r io.Reader) (*Envelope, error) {
+	envelope := &Envelope{}
+	if err := json.NewDecoder(r).Decode(envelope); err != nil {
+		return nil, fmt.Errorf("failed to decode envelope: %w", err)
+	}
+
+	return envelope, nil
+}
+
+// WriteVersion writes the VersionResponse of this protocol version. It is meant to be used by provider plugins.
+func WriteVersion(w io.Writer) error {
+	if err := json.NewEncoder(w).Encode(VersionResponse{ProtocolVersion: ProtocolVersion}); err != nil {
+		return fmt.Errorf("failed to encode version: %w", err)
+	}
+
+	return nil
+}
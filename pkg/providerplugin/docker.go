@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerplugin
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/SovereignCloudStack/csctl/pkg/pluginprotocol"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	nodeImagesFileName = "node-images.yaml"
+
+	dockerNodeImagesAPIVersion = "docker.infrastructure.clusterstack.x-k8s.io/v1alpha1"
+	dockerProviderAPIVersion   = "docker.csctl.clusterstack.x-k8s.io/v1alpha1"
+)
+
+// builtinProviders contains the providers that csctl handles without an external plugin.
+var builtinProviders = map[string]func(envelope *pluginprotocol.Envelope) error{
+	"docker": createDockerNodeImages,
+}
+
+// dockerNodeImages is the node-images.yaml of the docker provider.
+type dockerNodeImages struct {
+	APIVersion   string   `yaml:"apiVersion"`
+	DockerImages []string `yaml:"dockerImages"`
+}
+
+// createDockerNodeImages writes node-images.yaml to the release directory. Docker node images are not built
+// by csctl, so node-images.yaml of the cluster stack is used if it exists. Otherwise, it is generated from
+// the images in the provider config of csctl.yaml.
+func createDockerNodeImages(envelope *pluginprotocol.Envelope) error {
+	if err := envelope.CheckProviderAPIVersion(dockerProviderAPIVersion); err != nil {
+		return fmt.Errorf("failed to check provider config: %w", err)
+	}
+
+	dst := filepath.Join(envelope.ReleaseDir, nodeImagesFileName)
+
+	data, err := os.ReadFile(filepath.Join(envelope.ClusterStackPath, nodeImagesFileName))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to read %s: %w", nodeImagesFileName, err)
+		}
+
+		nodeImages := dockerNodeImages{APIVersion: dockerNodeImagesAPIVersion, DockerImages: []string{}}
+		for _, image := range envelope.Config.Config.Provider.Config.Images {
+			if image != nil {
+				nodeImages.DockerImages = append(nodeImages.DockerImages, *image)
+			}
+		}
+
+		data, err = yaml.Marshal(nodeImages)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %w", nodeImagesFileName, err)
+		}
+	}
+
+	if err := os.WriteFile(dst, data, os.FileMode(0o644)); err != nil {
+		return fmt.Errorf("failed to write %s: %w", nodeImagesFileName, err)
+	}
+
+	slog.Info("Created node images file with the built-in provider", "path", dst, "provider", envelope.Config.Config.Provider.Type)
+	return nil
+}
@@ -18,20 +18,50 @@ limitations under the License.
 package providerplugin
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/SovereignCloudStack/csctl/pkg/clusterstack"
+	"github.com/SovereignCloudStack/csctl/pkg/logging"
+	"github.com/SovereignCloudStack/csctl/pkg/pluginprotocol"
 )
 
+// EnvPluginPath is the environment variable with a colon-separated list of directories in which
+// plugins are searched before $PATH.
+const EnvPluginPath = "CSCTL_PLUGIN_PATH"
+
+// waitDelay is how long csctl waits for the output of a killed plugin to be closed. Children of the plugin, like
+// packer, may keep it open.
+const waitDelay = 5 * time.Second
+
 // GetProviderExecutable returns the path to the provider plugin (like "csctl-docker").
 // If there is not "config" for the provider in csctl.yaml, then "needed" is false and "path" is the empty string.
-func GetProviderExecutable(config *clusterstack.CsctlConfig) (needed bool, path string, err error) {
-	if len(config.Config.Provider.Config) == 0 {
+// The plugin is searched in the current working directory, in the directories of CSCTL_PLUGIN_PATH and in $PATH,
+// unless the path of the plugin is given with explicitPlugin, e.g. with --plugin.
+// If no plugin is found, but csctl has a built-in provider for the type, then "needed" is true and "path" is the empty string.
+func GetProviderExecutable(config *clusterstack.CsctlConfig, explicitPlugin string) (needed bool, path string, err error) {
+	if config.Config.Provider.Config.IsEmpty() {
 		return false, "", nil
 	}
+
+	if explicitPlugin != "" {
+		path, err := filepath.Abs(explicitPlugin)
+		if err != nil {
+			return false, "", fmt.Errorf("filepath.Abs(%q) failed: %w", explicitPlugin, err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return false, "", fmt.Errorf("could not find plugin %s: %w", path, err)
+		}
+		return true, path, nil
+	}
+
 	pluginName := "csctl-" + config.Config.Provider.Type
 	_, err = os.Stat(pluginName)
 	if err == nil {
@@ -41,32 +71,117 @@ func GetProviderExecutable(config *clusterstack.CsctlConfig) (needed bool, path
 		}
 		return true, path, nil
 	}
+
+	searched := []string{"current working directory"}
+
+	for _, dir := range filepath.SplitList(os.Getenv(EnvPluginPath)) {
+		if dir == "" {
+			continue
+		}
+		searched = append(searched, dir)
+
+		candidate := filepath.Join(dir, pluginName)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			path, err := filepath.Abs(candidate)
+			if err != nil {
+				return false, "", fmt.Errorf("filepath.Abs(%q) failed: %w", candidate, err)
+			}
+			return true, path, nil
+		}
+	}
+
 	path, err = exec.LookPath(pluginName)
 	if err != nil {
-		return false, "", fmt.Errorf("could not find plugin %s in $PATH or current working directory", pluginName)
+		if _, ok := builtinProviders[config.Config.Provider.Type]; ok {
+			return true, "", nil
+		}
+		searched = append(searched, filepath.SplitList(os.Getenv("PATH"))...)
+		return false, "", fmt.Errorf("could not find plugin %s, searched in: %s", pluginName, strings.Join(searched, ", "))
 	}
 	return true, path, nil
 }
 
 // CreateNodeImages calls the provider plugin command to create nodes images.
-func CreateNodeImages(config *clusterstack.CsctlConfig, clusterStackPath, clusterStackReleaseDir, nodeImageRegistry string) error {
-	needed, path, err := GetProviderExecutable(config)
+// See package pluginprotocol for the contract between csctl and the plugin.
+// Before creating the node images, the protocol version of the plugin is checked with the version subcommand.
+// The node image registry is an empty string if it was not specified. The plugin is searched like
+// GetProviderExecutable does, unless explicitPlugin is set.
+//
+// The plugin is killed if the context is canceled. If no plugin is found, a built-in provider is used if there is one.
+func CreateNodeImages(ctx context.Context, config *clusterstack.CsctlConfig, metadata *clusterstack.MetaData, clusterStackPath, clusterStackReleaseDir, nodeImageRegistry, explicitPlugin string) error {
+	needed, path, err := GetProviderExecutable(config, explicitPlugin)
 	if err != nil {
 		return err
 	}
 	if !needed {
-		fmt.Printf("No provider specific configuration in csctl.yaml. No need to call a plugin for provider %q\n",
-			config.Config.Provider.Type)
+		logging.FromContext(ctx).Info("No provider specific configuration in csctl.yaml. No need to call a plugin", "provider", config.Config.Provider.Type)
 		return nil
 	}
-	args := []string{"create-node-images", clusterStackPath, clusterStackReleaseDir, nodeImageRegistry}
-	fmt.Printf("Calling Provider Plugin: %s\n", path)
-	cmd := exec.Command(path, args...) // #nosec G204
-	cmd.Stdout = os.Stdout
+	envelopeData := pluginprotocol.Envelope{
+		ClusterStackPath:   clusterStackPath,
+		ReleaseDir:         clusterStackReleaseDir,
+		NodeImageRegistry:  nodeImageRegistry,
+		ProviderAPIVersion: config.Config.Provider.APIVersion,
+		Config:             config,
+		Metadata:           metadata,
+	}
+	if path == "" {
+		return builtinProviders[config.Config.Provider.Type](&envelopeData)
+	}
+
+	if err := checkProtocolVersion(ctx, path); err != nil {
+		return err
+	}
+
+	envelope, err := json.Marshal(envelopeData)
+	if err != nil {
+		return fmt.Errorf("failed to marshal plugin envelope: %w", err)
+	}
+
+	args := []string{pluginprotocol.CommandCreateNodeImages, clusterStackPath, clusterStackReleaseDir, nodeImageRegistry, pluginprotocol.ProtocolVersion}
+	logging.FromContext(ctx).Info("Calling provider plugin", "path", path)
+	cmd := exec.CommandContext(ctx, path, args...) // #nosec G204
+	cmd.WaitDelay = waitDelay
+	cmd.Stdin = bytes.NewReader(envelope)
+	// The output of the plugin is progress information like the logs of csctl.
+	cmd.Stdout = logging.ProgressWriter(ctx, logging.FromContext(ctx))
 	cmd.Stderr = os.Stderr
 	err = cmd.Run()
+	if ctx.Err() != nil {
+		return fmt.Errorf("provider plugin %s was canceled: %w", path, ctx.Err())
+	}
 	if err != nil {
 		return fmt.Errorf("cmd.Run() failed: %w", err)
 	}
 	return nil
 }
+
+// checkProtocolVersion calls the version subcommand of the plugin and compares its protocol version with the
+// protocol version of csctl. Plugins that do not implement the version subcommand are still called, but a warning is printed.
+func checkProtocolVersion(ctx context.Context, path string) error {
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, pluginprotocol.CommandVersion) // #nosec G204
+	cmd.WaitDelay = waitDelay
+	cmd.Stdout = &stdout
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return fmt.Errorf("provider plugin %s was canceled: %w", path, ctx.Err())
+	}
+
+	var version pluginprotocol.VersionResponse
+	if err == nil {
+		err = json.Unmarshal(stdout.Bytes(), &version)
+	}
+	if err != nil || version.ProtocolVersion == "" {
+		logging.FromContext(ctx).Warn("Provider plugin does not support the version subcommand. Assuming it is compatible",
+			"path", path, "protocolVersion", pluginprotocol.ProtocolVersion)
+		return nil
+	}
+
+	if version.ProtocolVersion != pluginprotocol.ProtocolVersion {
+		return fmt.Errorf("provider plugin %s implements protocol version %s, but csctl requires protocol version %s",
+			path, version.ProtocolVersion, pluginprotocol.ProtocolVersion)
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerplugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SovereignCloudStack/csctl/pkg/clusterstack"
+	"github.com/SovereignCloudStack/csctl/pkg/logging"
+	"github.com/SovereignCloudStack/csctl/pkg/pluginprotocol"
+)
+
+// writeSleepingPlugin writes a plugin that starts a sleeping child, writes its PID to the returned file and sleeps
+// itself, like a plugin whose packer build hangs.
+func writeSleepingPlugin(t *testing.T) (plugin, pidFile string) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake plugin is a shell script")
+	}
+
+	dir := t.TempDir()
+	plugin = filepath.Join(dir, "csctl-fake")
+	pidFile = filepath.Join(dir, "child.pid")
+
+	script := fmt.Sprintf(`#!/bin/sh
+case "$1" in
+%s)
+	echo '{"protocolVersion": "%s"}'
+	;;
+%s)
+	sleep 60 &
+	echo $! > %s.tmp
+	mv %s.tmp %s
+	exec sleep 60
+	;;
+esac
+`, pluginprotocol.CommandVersion, pluginprotocol.ProtocolVersion, pluginprotocol.CommandCreateNodeImages, pidFile, pidFile, pidFile)
+
+	if err := os.WriteFile(plugin, []byte(script), 0o700); err != nil { // #nosec G306
+		t.Fatal(err)
+	}
+
+	// The child is not killed with the plugin. Do not leave it behind.
+	t.Cleanup(func() {
+		data, err := os.ReadFile(filepath.Clean(pidFile))
+		if err != nil {
+			return
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil {
+			return
+		}
+		if process, err := os.FindProcess(pid); err == nil {
+			_ = process.Kill()
+		}
+	})
+
+	return plugin, pidFile
+}
+
+func TestCreateNodeImagesCanceled(t *testing.T) {
+	config := &clusterstack.CsctlConfig{}
+	config.Config.Provider.Type = "fake"
+	config.Config.Provider.Config.Method = clusterstack.ProviderConfigMethodBuild
+
+	tests := []struct {
+		name  string
+		quiet bool
+	}{
+		{name: "progress output"},
+		// The output of the plugin is discarded, which must not make csctl wait for the child of the plugin.
+		{name: "quiet", quiet: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin, pidFile := writeSleepingPlugin(t)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if tt.quiet {
+				logger, err := logging.New(io.Discard, "error", logging.FormatText)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ctx = logging.IntoContext(ctx, logger)
+			}
+
+			// Cancel as soon as the plugin started its child, so that the child still runs when the plugin is killed.
+			go func() {
+				for ctx.Err() == nil {
+					if _, err := os.Stat(pidFile); err == nil {
+						cancel()
+						return
+					}
+					time.Sleep(10 * time.Millisecond)
+				}
+			}()
+
+			start := time.Now()
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- CreateNodeImages(ctx, config, &clusterstack.MetaData{}, t.TempDir(), t.TempDir(), "", plugin)
+			}()
+
+			select {
+			case err := <-errCh:
+				if !errors.Is(err, context.Canceled) {
+					t.Fatalf("CreateNodeImages() error = %v, want it to be canceled", err)
+				}
+			case <-time.After(20 * time.Second):
+				t.Fatal("CreateNodeImages() did not return after the context was canceled")
+			}
+
+			// Waiting for the output of the child would take waitDelay.
+			if elapsed := time.Since(start); elapsed > waitDelay/2 {
+				t.Errorf("CreateNodeImages() returned after %s, want it to return shortly after the cancellation", elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,358 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package release builds cluster stack releases. It is the programmatic API behind "csctl create".
+package release
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/SovereignCloudStack/csctl/pkg/clusterstack"
+	"github.com/SovereignCloudStack/csctl/pkg/hash"
+	"github.com/SovereignCloudStack/csctl/pkg/logging"
+	"github.com/SovereignCloudStack/csctl/pkg/providerplugin"
+	"github.com/SovereignCloudStack/csctl/pkg/template"
+	"gopkg.in/yaml.v3"
+)
+
+// Options contains everything that is needed to build a release.
+// The versions of the release are taken from Metadata, which is computed by the caller, e.g. with clusterstack.HandleHashMode.
+type Options struct {
+	// ClusterStackPath is the path to the cluster stack.
+	ClusterStackPath string
+	// ReleaseDir is the directory in which the release is created.
+	ReleaseDir string
+	// TmpDir is the directory for the templated cluster stack. It is owned by the caller.
+	// If empty, a temporary directory is created and removed afterwards.
+	TmpDir string
+	// NewClusterStackConvention is true if the cluster addons are configured with clusteraddon.yaml.
+	NewClusterStackConvention bool
+	Config                    *clusterstack.CsctlConfig
+	Metadata                  *clusterstack.MetaData
+	// ReleaseHash is the hash of the cluster stack, which is written to hashes.json.
+	ReleaseHash       hash.ReleaseHash
+	NodeImageRegistry string
+	Compression       template.Compression
+	// TemplateValues are additional values for templating.
+	TemplateValues  map[string]interface{}
+	StrictTemplates bool
+	HelmOptions     template.HelmOptions
+	// Plugin is the path of the provider plugin. If it is empty, the plugin is searched like
+	// providerplugin.GetProviderExecutable does.
+	Plugin string
+	// PluginTimeout is the maximum duration of the provider plugin call. Zero means no timeout.
+	PluginTimeout time.Duration
+	// BuildInfo is written to metadata.yaml if it is set.
+	BuildInfo *clusterstack.BuildInfo
+	// SkipNodeImages skips calling the provider plugin, e.g. for a dry run.
+	SkipNodeImages bool
+}
+
+// Result describes a built release.
+type Result struct {
+	ReleaseDir string
+	Metadata   *clusterstack.MetaData
+}
+
+// Build builds the release: it templates the cluster stack, packages the charts and calls the provider plugin
+// to create the node images. If the provider plugin is canceled, the incomplete release directory is removed.
+// The layout of the cluster stack is validated first, so that missing directories and charts are reported by path.
+func Build(ctx context.Context, opts Options) (_ *Result, reterr error) {
+	// Fail with the missing path instead of a confusing error of helm while packaging.
+	if err := clusterstack.ValidateLayout(opts.ClusterStackPath); err != nil {
+		return nil, fmt.Errorf("invalid cluster stack %s: %w", opts.ClusterStackPath, err)
+	}
+
+	tmpDir := opts.TmpDir
+	if tmpDir == "" {
+		dir, err := os.MkdirTemp("", "csctl-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(dir)
+		tmpDir = dir
+	}
+
+	if err := os.MkdirAll(opts.ReleaseDir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	defer func() {
+		// The release is incomplete if the build got canceled, e.g. by SIGINT.
+		if reterr != nil && ctx.Err() != nil {
+			if err := os.RemoveAll(opts.ReleaseDir); err != nil {
+				reterr = errors.Join(reterr, fmt.Errorf("failed to remove release directory %s: %w", opts.ReleaseDir, err))
+			}
+		}
+	}()
+	logging.FromContext(ctx).Info("Creating output", "path", opts.ReleaseDir)
+	// Write the current hash
+	if err := WriteReleaseHash(opts.ReleaseDir, opts.ReleaseHash); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("build was canceled: %w", err)
+	}
+
+	// Build all the templated output and put it in a tmp directory
+	if err := template.GenerateOutputFromTemplate(ctx, opts.ClusterStackPath, tmpDir, opts.Config, opts.Metadata, opts.TemplateValues, opts.StrictTemplates); err != nil {
+		return nil, fmt.Errorf("failed to generate tmp output: %w", err)
+	}
+
+	// Overwrite ClusterAddonVersion in cluster-addon/*/Chart.yaml
+	if err := overwriteClusterAddonVersion(ctx, tmpDir, opts.Metadata.Versions.Components.ClusterAddon); err != nil {
+		return nil, fmt.Errorf("failed to overwrite ClusterAddonVersion in tmp output: %w", err)
+	}
+
+	// Overwrite ClusterClassVersion in cluster-class/Chart.yaml
+	clusterClassChartYaml := filepath.Join(tmpDir, "cluster-class", "Chart.yaml")
+	if err := overwriteVersionInFile(ctx, clusterClassChartYaml, opts.Metadata.Versions.ClusterStack); err != nil {
+		return nil, fmt.Errorf("failed to overwrite ClusterClassVersion in %s output: %w", clusterClassChartYaml, err)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("build was canceled: %w", err)
+	}
+
+	// Package Helm from the tmp directory to the release directory
+	if err := template.CreatePackage(ctx, tmpDir, opts.ReleaseDir, opts.NewClusterStackConvention, opts.Config, opts.Metadata, opts.Compression, opts.HelmOptions); err != nil {
+		return nil, fmt.Errorf("failed to create template package: %w", err)
+	}
+
+	if opts.NewClusterStackConvention {
+		// Copy the clusteraddon.yaml config to release if new way
+		clusterAddonData, err := os.ReadFile(filepath.Join(opts.ClusterStackPath, "clusteraddon.yaml"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read clusteraddon.yaml: %w", err)
+		}
+
+		if err := os.WriteFile(filepath.Join(opts.ReleaseDir, "clusteraddon.yaml"), clusterAddonData, os.FileMode(0o644)); err != nil {
+			return nil, fmt.Errorf("failed to write clusteraddon.yaml: %w", err)
+		}
+	} else {
+		// Copy the cluster-addon-values.yaml config to release if old way
+		clusterAddonData, err := os.ReadFile(filepath.Join(tmpDir, "cluster-addon-values.yaml"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cluster-addon-values.yaml: %w", err)
+		}
+
+		if err := os.WriteFile(filepath.Join(opts.ReleaseDir, "cluster-addon-values.yaml"), clusterAddonData, os.FileMode(0o644)); err != nil {
+			return nil, fmt.Errorf("failed to write cluster-addon-values.yaml: %w", err)
+		}
+	}
+
+	// Put the final metadata file into the output directory. The metadata of the caller is copied, so that setting
+	// the build info does not change it.
+	metadata := *opts.Metadata
+	if opts.BuildInfo != nil {
+		metadata.BuildInfo = opts.BuildInfo
+	}
+
+	metaDataByte, err := yaml.Marshal(&metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal metadata yaml: %w", err)
+	}
+
+	metadataFile, err := os.Create(filepath.Join(opts.ReleaseDir, "metadata.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metadata file: %w", err)
+	}
+	defer metadataFile.Close()
+
+	if _, err := metadataFile.Write(metaDataByte); err != nil {
+		return nil, fmt.Errorf("failed to write metadata: %w", err)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("build was canceled: %w", err)
+	}
+
+	if opts.SkipNodeImages {
+		logging.FromContext(ctx).Info("Skipping node images")
+		return &Result{
+			ReleaseDir: opts.ReleaseDir,
+			Metadata:   &metadata,
+		}, nil
+	}
+
+	pluginCtx := ctx
+	if opts.PluginTimeout > 0 {
+		var cancel context.CancelFunc
+		pluginCtx, cancel = context.WithTimeout(ctx, opts.PluginTimeout)
+		defer cancel()
+	}
+
+	err = providerplugin.CreateNodeImages(pluginCtx,
+		opts.Config,
+		&metadata,
+		opts.ClusterStackPath,
+		opts.ReleaseDir,
+		opts.NodeImageRegistry,
+		opts.Plugin)
+	if err != nil {
+		if pluginCtx.Err() != nil {
+			// The release is incomplete if the plugin got canceled.
+			if err := os.RemoveAll(opts.ReleaseDir); err != nil {
+				return nil, fmt.Errorf("failed to remove release directory %s: %w", opts.ReleaseDir, err)
+			}
+		}
+		return nil, fmt.Errorf("providerplugin.CreateNodeImages() failed: %w", err)
+	}
+
+	return &Result{
+		ReleaseDir: opts.ReleaseDir,
+		Metadata:   &metadata,
+	}, nil
+}
+
+// WriteReleaseHash writes the release hash to hashes.json in the release directory.
+func WriteReleaseHash(releaseDir string, releaseHash hash.ReleaseHash) error {
+	hashJSONData, err := json.MarshalIndent(releaseHash, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal hash json: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(releaseDir, "hashes.json"), hashJSONData, os.FileMode(0o644)); err != nil {
+		return fmt.Errorf("failed to write current release hash: %w", err)
+	}
+
+	return nil
+}
+
+func overwriteClusterAddonVersion(ctx context.Context, tmpDir, clusterAddonVersion string) error {
+	// The cluster addon is either a single chart or split into one chart per subdirectory.
+	var files []string
+	for _, g := range []string{
+		filepath.Join(tmpDir, "cluster-addon", "Chart.yaml"),
+		filepath.Join(tmpDir, "cluster-addon", "*", "Chart.yaml"),
+	} {
+		matches, err := filepath.Glob(g)
+		if err != nil {
+			return fmt.Errorf("glob for %s failed: %w", g, err)
+		}
+		files = append(files, matches...)
+	}
+
+	for _, chartYaml := range files {
+		err := overwriteVersionInFile(ctx, chartYaml, clusterAddonVersion)
+		if err != nil {
+			return fmt.Errorf("failed to replace version in %s: %w", chartYaml, err)
+		}
+	}
+	return nil
+}
+
+// overwriteVersionInFile replaces "version: v123" with newVersion.
+// Only the version value is replaced, so that comments and formatting of the file are preserved.
+// If there is no version, it is added at the end of the file.
+func overwriteVersionInFile(ctx context.Context, chartYaml, newVersion string) error {
+	chartYaml = filepath.Clean(chartYaml)
+	data, err := os.ReadFile(chartYaml)
+	if err != nil {
+		return fmt.Errorf("reading file failed: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed parsing: %w", err)
+	}
+
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("failed to read yaml: expected a mapping")
+	}
+	mapping := doc.Content[0]
+
+	var versionNode *yaml.Node
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == "version" {
+			versionNode = mapping.Content[i+1]
+			break
+		}
+	}
+
+	var out []byte
+	if versionNode == nil {
+		logging.FromContext(ctx).Info("Adding version", "path", chartYaml, "version", newVersion)
+		// Keep the line endings of the file, e.g. CRLF of files edited on Windows.
+		newline := "\n"
+		if bytes.Contains(data, []byte("\r\n")) {
+			newline = "\r\n"
+		}
+		out = data
+		if len(out) > 0 && !bytes.HasSuffix(out, []byte("\n")) {
+			out = append(out, newline...)
+		}
+		out = append(out, []byte(fmt.Sprintf("version: %s%s", newVersion, newline))...)
+	} else {
+		if versionNode.Kind != yaml.ScalarNode {
+			return fmt.Errorf("failed to read version in yaml")
+		}
+		logging.FromContext(ctx).Info("Updating version", "path", chartYaml, "from", versionNode.Value, "to", newVersion)
+
+		out, err = replaceScalarValue(data, versionNode, newVersion)
+		if err != nil {
+			return fmt.Errorf("failed to replace version: %w", err)
+		}
+	}
+
+	err = os.WriteFile(chartYaml, out, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed write yaml to file: %w", err)
+	}
+	return nil
+}
+
+// replaceScalarValue replaces the value of the scalar node in data with value, leaving all other bytes untouched.
+func replaceScalarValue(data []byte, node *yaml.Node, value string) ([]byte, error) {
+	token := node.Value
+	switch node.Style {
+	case yaml.DoubleQuotedStyle:
+		token = `"` + token + `"`
+		value = `"` + value + `"`
+	case yaml.SingleQuotedStyle:
+		token = "'" + token + "'"
+		value = "'" + value + "'"
+	case yaml.TaggedStyle, yaml.LiteralStyle, yaml.FoldedStyle, yaml.FlowStyle:
+		return nil, fmt.Errorf("unsupported style of value %q", node.Value)
+	}
+
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	if node.Line < 1 || node.Line > len(lines) {
+		return nil, fmt.Errorf("line %d of value %q out of range", node.Line, node.Value)
+	}
+
+	line := lines[node.Line-1]
+	start := node.Column - 1
+	if start < 0 || start+len(token) > len(line) || string(line[start:start+len(token)]) != token {
+		return nil, fmt.Errorf("failed to find value %q in line %d", node.Value, node.Line)
+	}
+
+	newLine := make([]byte, 0, len(line)-len(token)+len(value))
+	newLine = append(newLine, line[:start]...)
+	newLine = append(newLine, value...)
+	newLine = append(newLine, line[start+len(token):]...)
+	lines[node.Line-1] = newLine
+
+	return bytes.Join(lines, nil), nil
+}
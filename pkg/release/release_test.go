@@ -0,0 +1,266 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/SovereignCloudStack/csctl/pkg/clusterstack"
+	"github.com/SovereignCloudStack/csctl/pkg/hash"
+	"github.com/SovereignCloudStack/csctl/pkg/mediatype"
+	"github.com/SovereignCloudStack/csctl/pkg/template"
+)
+
+func TestBuild(t *testing.T) {
+	ctx := context.Background()
+	clusterStackPath := filepath.Join("..", "..", "tests", "cluster-stacks", "docker", "valencia")
+
+	config, err := clusterstack.GetCsctlConfig(clusterStackPath)
+	if err != nil {
+		t.Fatalf("failed to get config: %v", err)
+	}
+
+	releaseHash, err := hash.GetHash(ctx, clusterStackPath)
+	if err != nil {
+		t.Fatalf("failed to get hash: %v", err)
+	}
+
+	metadata, err := clusterstack.HandleHashMode(releaseHash, config.Config.KubernetesVersion)
+	if err != nil {
+		t.Fatalf("failed to get metadata: %v", err)
+	}
+
+	releaseDir := filepath.Join(t.TempDir(), "release")
+	result, err := Build(ctx, Options{
+		ClusterStackPath:          clusterStackPath,
+		ReleaseDir:                releaseDir,
+		TmpDir:                    t.TempDir(),
+		NewClusterStackConvention: true,
+		Config:                    config,
+		Metadata:                  metadata,
+		ReleaseHash:               releaseHash,
+		Compression:               template.DefaultCompression,
+		BuildInfo:                 &clusterstack.BuildInfo{CsctlVersion: "v0.0.1"},
+	})
+	if err != nil {
+		t.Fatalf("failed to build release: %v", err)
+	}
+
+	if metadata.BuildInfo != nil {
+		t.Error("Build() changed the metadata of the caller")
+	}
+	if result.Metadata.BuildInfo == nil || result.Metadata.BuildInfo.CsctlVersion != "v0.0.1" {
+		t.Errorf("got build info %+v in the result, want version v0.0.1", result.Metadata.BuildInfo)
+	}
+
+	if result.ReleaseDir != releaseDir {
+		t.Errorf("got release directory %s, want %s", result.ReleaseDir, releaseDir)
+	}
+
+	built, err := clusterstack.ParseMetaData(releaseDir)
+	if err != nil {
+		t.Fatalf("failed to parse metadata.yaml of the release: %v", err)
+	}
+	if built.Versions.ClusterStack != metadata.Versions.ClusterStack {
+		t.Errorf("got cluster stack version %s, want %s", built.Versions.ClusterStack, metadata.Versions.ClusterStack)
+	}
+	if built.BuildInfo == nil || built.BuildInfo.CsctlVersion != "v0.0.1" {
+		t.Errorf("got build info %+v in metadata.yaml, want version v0.0.1", built.BuildInfo)
+	}
+
+	entries, err := os.ReadDir(releaseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	for _, want := range []string{
+		"clusteraddon.yaml",
+		"metadata.yaml",
+		"docker-valencia-1-27-cluster-class-" + metadata.Versions.ClusterStack + ".tgz",
+		"docker-valencia-1-27-cluster-addon-" + metadata.Versions.Components.ClusterAddon + ".tgz",
+	} {
+		if !slices.Contains(names, want) {
+			t.Errorf("release does not contain %s, got %s", want, strings.Join(names, ", "))
+		}
+	}
+}
+
+func TestBuildMissingClusterClass(t *testing.T) {
+	clusterStackPath := t.TempDir()
+	if err := os.Mkdir(filepath.Join(clusterStackPath, "cluster-addon"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	releaseDir := filepath.Join(t.TempDir(), "release")
+	_, err := Build(context.Background(), Options{
+		ClusterStackPath: clusterStackPath,
+		ReleaseDir:       releaseDir,
+		TmpDir:           t.TempDir(),
+	})
+
+	want := filepath.Join(clusterStackPath, "cluster-class", "Chart.yaml") + " is missing"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("Build() error = %v, want it to contain %q", err, want)
+	}
+
+	if _, err := os.Stat(releaseDir); !os.IsNotExist(err) {
+		t.Errorf("expected no release directory, got %v", err)
+	}
+}
+
+func TestBuildSplitClusterAddon(t *testing.T) {
+	ctx := context.Background()
+	clusterStackPath := filepath.Join("..", "..", "tests", "cluster-stacks", "docker", "porto")
+
+	config, err := clusterstack.GetCsctlConfig(clusterStackPath)
+	if err != nil {
+		t.Fatalf("failed to get config: %v", err)
+	}
+
+	releaseHash, err := hash.GetHash(ctx, clusterStackPath)
+	if err != nil {
+		t.Fatalf("failed to get hash: %v", err)
+	}
+
+	metadata := &clusterstack.MetaData{APIVersion: "metadata.clusterstack.x-k8s.io/v1alpha1"}
+	metadata.Versions.Kubernetes = config.Config.KubernetesVersion
+	metadata.Versions.ClusterStack = "v2"
+	metadata.Versions.Components.ClusterAddon = "v2"
+	metadata.Versions.Components.NodeImage = "v1"
+
+	releaseDir := filepath.Join(t.TempDir(), "release")
+	if _, err := Build(ctx, Options{
+		ClusterStackPath: clusterStackPath,
+		ReleaseDir:       releaseDir,
+		TmpDir:           t.TempDir(),
+		Config:           config,
+		Metadata:         metadata,
+		ReleaseHash:      releaseHash,
+		Compression:      template.DefaultCompression,
+	}); err != nil {
+		t.Fatalf("failed to build release: %v", err)
+	}
+
+	// Both charts are packaged with the bumped version and published as cluster addon.
+	for _, name := range []string{"cni-v2.tgz", "metrics-server-v2.tgz"} {
+		if _, err := os.Stat(filepath.Join(releaseDir, name)); err != nil {
+			t.Errorf("release does not contain %s: %v", name, err)
+		}
+
+		kind, err := mediatype.KindOf(name)
+		if err != nil || kind != mediatype.KindClusterAddon {
+			t.Errorf("kind of %s = %q, %v, want %q", name, kind, err, mediatype.KindClusterAddon)
+		}
+	}
+}
+
+func TestOverwriteVersionInFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "comments and key order are preserved",
+			input: "# The chart of the cluster addon.\nname: cni\n# version will be overwritten by csctl\nversion: v0 # comment on the version\ntype: application\napiVersion: v2\n",
+			want:  "# The chart of the cluster addon.\nname: cni\n# version will be overwritten by csctl\nversion: v3 # comment on the version\ntype: application\napiVersion: v2\n",
+		},
+		{
+			name:  "double quoted version",
+			input: "name: cni\nversion: \"v0\"\n",
+			want:  "name: cni\nversion: \"v3\"\n",
+		},
+		{
+			name:  "single quoted version",
+			input: "name: cni\nversion: 'v0'\n",
+			want:  "name: cni\nversion: 'v3'\n",
+		},
+		{
+			name:  "only the version key is replaced",
+			input: "name: cni\ndependencies:\n- name: dependency\n  version: v0\nversion: v0\n",
+			want:  "name: cni\ndependencies:\n- name: dependency\n  version: v0\nversion: v3\n",
+		},
+		{
+			name:  "missing version is appended",
+			input: "apiVersion: v2\nname: cni\n",
+			want:  "apiVersion: v2\nname: cni\nversion: v3\n",
+		},
+		{
+			name:  "missing version is appended after the last line without newline",
+			input: "apiVersion: v2\nname: cni",
+			want:  "apiVersion: v2\nname: cni\nversion: v3\n",
+		},
+		{
+			name:  "CRLF",
+			input: "apiVersion: v2\r\n# comment\r\nversion: v0\r\nname: cni\r\n",
+			want:  "apiVersion: v2\r\n# comment\r\nversion: v3\r\nname: cni\r\n",
+		},
+		{
+			name:  "CRLF with missing version",
+			input: "apiVersion: v2\r\nname: cni\r\n",
+			want:  "apiVersion: v2\r\nname: cni\r\nversion: v3\r\n",
+		},
+		{
+			name:    "no mapping",
+			input:   "- name: cni\n",
+			wantErr: true,
+		},
+		{
+			name:    "version is no scalar",
+			input:   "version:\n  major: 1\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chartYaml := filepath.Join(t.TempDir(), "Chart.yaml")
+			if err := os.WriteFile(chartYaml, []byte(tt.input), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			err := overwriteVersionInFile(context.Background(), chartYaml, "v3")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, err := os.ReadFile(chartYaml)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// CompressionGzip compresses tar packages with gzip. This is the default.
+	CompressionGzip = "gzip"
+
+	// CompressionZstd compresses tar packages with zstd.
+	CompressionZstd = "zstd"
+)
+
+// Compression defines how tar packages are compressed.
+type Compression struct {
+	// Algorithm is either CompressionGzip or CompressionZstd.
+	Algorithm string
+	// Level is the compression level of the algorithm. Zero means the default level.
+	Level int
+}
+
+// DefaultCompression is the compression used if nothing else is specified.
+var DefaultCompression = Compression{Algorithm: CompressionGzip}
+
+// ParseCompression parses a compression in the format <algorithm>[:<level>], e.g. gzip, gzip:9 or zstd:3.
+func ParseCompression(str string) (Compression, error) {
+	algorithm, levelStr, hasLevel := strings.Cut(str, ":")
+
+	compression := Compression{Algorithm: algorithm}
+
+	if hasLevel {
+		level, err := strconv.Atoi(levelStr)
+		if err != nil {
+			return Compression{}, fmt.Errorf("invalid compression level %q: %w", levelStr, err)
+		}
+		compression.Level = level
+	}
+
+	if err := compression.Validate(); err != nil {
+		return Compression{}, err
+	}
+
+	return compression, nil
+}
+
+// Validate validates the compression.
+func (c Compression) Validate() error {
+	switch c.Algorithm {
+	case CompressionGzip:
+		if c.Level != 0 && (c.Level < gzip.BestSpeed || c.Level > gzip.BestCompression) {
+			return fmt.Errorf("invalid gzip compression level %d: must be 0 (default) or %d-%d", c.Level, gzip.BestSpeed, gzip.BestCompression)
+		}
+	case CompressionZstd:
+		if c.Level < 0 || c.Level > 22 {
+			return fmt.Errorf("invalid zstd compression level %d: must be 0 (default) or 1-22", c.Level)
+		}
+	default:
+		return fmt.Errorf("invalid compression algorithm %q: supported are %q and %q", c.Algorithm, CompressionGzip, CompressionZstd)
+	}
+
+	return nil
+}
+
+// FileExtension returns the extension of tar packages compressed with this compression.
+func (c Compression) FileExtension() string {
+	if c.Algorithm == CompressionZstd {
+		return ".tar.zst"
+	}
+	return ".tgz"
+}
+
+// newWriter returns a writer compressing into w.
+func (c Compression) newWriter(w io.Writer) (io.WriteCloser, error) {
+	switch c.Algorithm {
+	case CompressionZstd:
+		opts := []zstd.EOption{}
+		if c.Level != 0 {
+			opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(c.Level)))
+		}
+		zw, err := zstd.NewWriter(w, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd writer: %w", err)
+		}
+		return zw, nil
+	default:
+		level := c.Level
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		gw, err := gzip.NewWriterLevel(w, level)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create gzip writer: %w", err)
+		}
+		return gw, nil
+	}
+}
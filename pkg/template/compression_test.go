@@ -0,0 +1,56 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import "testing"
+
+func TestParseCompression(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Compression
+		wantErr bool
+	}{
+		{input: "gzip", want: Compression{Algorithm: CompressionGzip}},
+		{input: "gzip:9", want: Compression{Algorithm: CompressionGzip, Level: 9}},
+		{input: "zstd", want: Compression{Algorithm: CompressionZstd}},
+		{input: "zstd:0", want: Compression{Algorithm: CompressionZstd}},
+		{input: "zstd:22", want: Compression{Algorithm: CompressionZstd, Level: 22}},
+		{input: "zstd:23", wantErr: true},
+		{input: "zstd:-1", wantErr: true},
+		{input: "gzip:10", wantErr: true},
+		{input: "gzip:fast", wantErr: true},
+		{input: "xz", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseCompression(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -18,21 +18,51 @@ package template
 
 import (
 	"archive/tar"
-	"compress/gzip"
+	"context"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/SovereignCloudStack/csctl/pkg/clusterstack"
+	"github.com/SovereignCloudStack/csctl/pkg/logging"
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/lint"
+	"helm.sh/helm/v3/pkg/lint/support"
+	"helm.sh/helm/v3/pkg/registry"
 )
 
-// CreatePackage creates the package for release.
-func CreatePackage(src, dst string, newType bool, config *clusterstack.CsctlConfig, metadata *clusterstack.MetaData) error {
-	fmt.Printf("path now: %q\n", filepath.Join(src, "cluster-class"))
-	if err := createHelmPackage(filepath.Join(src, "cluster-class"), dst); err != nil {
+// HelmOptions configures how helm charts are packaged.
+type HelmOptions struct {
+	// UpdateDependencies downloads the dependencies of charts into their charts directory before packaging,
+	// like "helm dependency build". Versions of Chart.lock are used, if present.
+	UpdateDependencies bool
+	// SkipLint skips linting the charts before packaging.
+	SkipLint bool
+	// StrictLint makes lint warnings fail packaging. Without it, only lint errors do.
+	StrictLint bool
+	// Sign creates a provenance file <package>.tgz.prov next to each helm package.
+	Sign bool
+	// Key is the name of the key to sign with.
+	Key string
+	// Keyring is the path to the keyring that contains the secret key.
+	Keyring string
+	// PassphraseFile is the file that contains the passphrase of the key. "-" reads it from stdin.
+	PassphraseFile string
+}
+
+// CreatePackage creates the package for release. The compression is used for tar packages,
+// helm packages are always compressed with gzip.
+func CreatePackage(ctx context.Context, src, dst string, newType bool, config *clusterstack.CsctlConfig, metadata *clusterstack.MetaData, compression Compression, helmOptions HelmOptions) error {
+	logger := logging.FromContext(ctx)
+
+	logger.Info("Packaging chart", "path", filepath.Join(src, "cluster-class"))
+	if err := createHelmPackage(ctx, filepath.Join(src, "cluster-class"), dst, helmOptions); err != nil {
 		return fmt.Errorf("failed to create package for ClusterClass: %w", err)
 	}
 
@@ -42,23 +72,90 @@ func CreatePackage(src, dst string, newType bool, config *clusterstack.CsctlConf
 	}
 
 	if newType {
-		clusterAddonDst := filepath.Join(dst, fmt.Sprintf("%s-%s-%s-cluster-addon-%s.tgz", config.Config.Provider.Type, config.Config.ClusterStackName, kubernetesVerion.String(), metadata.Versions.Components.ClusterAddon))
-		if err := createTarPackage(filepath.Join(src, "cluster-addon"), clusterAddonDst); err != nil {
+		clusterAddonDst := filepath.Join(dst, fmt.Sprintf("%s-%s-%s-cluster-addon-%s%s", config.Config.Provider.Type, config.Config.ClusterStackName, kubernetesVerion.String(), metadata.Versions.Components.ClusterAddon, compression.FileExtension()))
+
+		chartYamls, err := filepath.Glob(filepath.Join(src, "cluster-addon", "*", "Chart.yaml"))
+		if err != nil {
+			return fmt.Errorf("glob for charts in %s failed: %w", filepath.Join(src, "cluster-addon"), err)
+		}
+
+		for _, chartYaml := range chartYamls {
+			if helmOptions.UpdateDependencies {
+				if err := buildHelmDependencies(ctx, filepath.Dir(chartYaml)); err != nil {
+					return fmt.Errorf("failed to build dependencies for ClusterAddon: %w", err)
+				}
+			}
+
+			if !helmOptions.SkipLint {
+				if err := lintHelmChart(ctx, filepath.Dir(chartYaml), helmOptions.StrictLint); err != nil {
+					return fmt.Errorf("failed to lint ClusterAddon: %w", err)
+				}
+			}
+		}
+
+		if err := createTarPackage(filepath.Join(src, "cluster-addon"), clusterAddonDst, compression); err != nil {
 			return fmt.Errorf("failed to create package for ClusterAddon: %w", err)
 		}
 	} else {
-		fmt.Printf("path now: %q\n", filepath.Join(src, "cluster-addon"))
-		if err := createHelmPackage(filepath.Join(src, "cluster-addon"), dst); err != nil {
-			return fmt.Errorf("failed to create helm package for ClusterAddon: %w", err)
+		clusterAddonCharts, err := getClusterAddonCharts(filepath.Join(src, "cluster-addon"))
+		if err != nil {
+			return fmt.Errorf("failed to get ClusterAddon charts: %w", err)
+		}
+
+		for _, clusterAddonChart := range clusterAddonCharts {
+			logger.Info("Packaging chart", "path", clusterAddonChart)
+			if err := createHelmPackage(ctx, clusterAddonChart, dst, helmOptions); err != nil {
+				return fmt.Errorf("failed to create helm package for ClusterAddon: %w", err)
+			}
 		}
 	}
 
 	return nil
 }
 
-func createHelmPackage(src, dst string) error {
+// getClusterAddonCharts returns the chart directories of the cluster addon. This is either the
+// cluster addon directory itself or, if it doesn't contain a Chart.yaml, each subdirectory with a Chart.yaml.
+func getClusterAddonCharts(clusterAddonDir string) ([]string, error) {
+	if _, err := os.Stat(filepath.Join(clusterAddonDir, "Chart.yaml")); err == nil {
+		return []string{clusterAddonDir}, nil
+	}
+
+	chartYamls, err := filepath.Glob(filepath.Join(clusterAddonDir, "*", "Chart.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("glob for charts in %s failed: %w", clusterAddonDir, err)
+	}
+
+	if len(chartYamls) == 0 {
+		return nil, fmt.Errorf("no Chart.yaml found in %s", clusterAddonDir)
+	}
+
+	charts := make([]string, 0, len(chartYamls))
+	for _, chartYaml := range chartYamls {
+		charts = append(charts, filepath.Dir(chartYaml))
+	}
+
+	return charts, nil
+}
+
+func createHelmPackage(ctx context.Context, src, dst string, helmOptions HelmOptions) error {
+	if helmOptions.UpdateDependencies {
+		if err := buildHelmDependencies(ctx, src); err != nil {
+			return err
+		}
+	}
+
+	if !helmOptions.SkipLint {
+		if err := lintHelmChart(ctx, src, helmOptions.StrictLint); err != nil {
+			return err
+		}
+	}
+
 	helmPkg := action.NewPackage()
 	helmPkg.Destination = dst
+	helmPkg.Sign = helmOptions.Sign
+	helmPkg.Key = helmOptions.Key
+	helmPkg.Keyring = helmOptions.Keyring
+	helmPkg.PassphraseFile = helmOptions.PassphraseFile
 
 	_, err := helmPkg.Run(src, map[string]interface{}{})
 	if err != nil {
@@ -68,17 +165,78 @@ func createHelmPackage(src, dst string) error {
 	return nil
 }
 
-func createTarPackage(src, dst string) error {
+// buildHelmDependencies downloads the dependencies of the chart into its charts directory like "helm dependency build".
+// Without Chart.lock, the dependencies are resolved like "helm dependency update".
+func buildHelmDependencies(ctx context.Context, chartPath string) error {
+	settings := cli.New()
+
+	registryClient, err := registry.NewClient(registry.ClientOptCredentialsFile(settings.RegistryConfig))
+	if err != nil {
+		return fmt.Errorf("failed to create helm registry client: %w", err)
+	}
+
+	out := logging.ProgressWriter(ctx, logging.FromContext(ctx))
+	if out == nil {
+		out = io.Discard
+	}
+
+	manager := &downloader.Manager{
+		Out:              out,
+		ChartPath:        chartPath,
+		Getters:          getter.All(settings),
+		RegistryClient:   registryClient,
+		RepositoryConfig: settings.RepositoryConfig,
+		RepositoryCache:  settings.RepositoryCache,
+	}
+
+	if err := manager.Build(); err != nil {
+		return fmt.Errorf("failed to build helm dependencies of %s: %w", chartPath, err)
+	}
+
+	return nil
+}
+
+// lintHelmChart lints the chart like "helm lint". Errors make linting fail, warnings only if strict is set,
+// like "helm lint --strict".
+func lintHelmChart(ctx context.Context, chartPath string, strict bool) error {
+	linter := lint.All(chartPath, nil, "", false)
+
+	failSeverity := support.ErrorSev
+	if strict {
+		failSeverity = support.WarningSev
+	}
+
+	logger := logging.FromContext(ctx)
+	for _, message := range linter.Messages {
+		switch {
+		case message.Severity >= support.ErrorSev:
+			logger.Error(message.Error(), "chart", chartPath)
+		case message.Severity >= support.WarningSev:
+			logger.Warn(message.Error(), "chart", chartPath)
+		}
+	}
+
+	if linter.HighestSeverity >= failSeverity {
+		return fmt.Errorf("linting chart %s failed, use --skip-lint to package it anyway", chartPath)
+	}
+
+	return nil
+}
+
+func createTarPackage(src, dst string, compression Compression) error {
 	outFile, err := os.Create(filepath.Clean(dst))
 	if err != nil {
 		return fmt.Errorf("failed to create tar output destination directory: %w", err)
 	}
 	defer outFile.Close()
 
-	gw := gzip.NewWriter(outFile)
-	defer gw.Close()
+	cw, err := compression.newWriter(outFile)
+	if err != nil {
+		return fmt.Errorf("failed to create compression writer: %w", err)
+	}
+	defer cw.Close()
 
-	tw := tar.NewWriter(gw)
+	tw := tar.NewWriter(cw)
 	defer tw.Close()
 
 	if err := filepath.Walk(src, func(path string, info fs.FileInfo, err error) error {
@@ -104,6 +262,7 @@ func createTarPackage(src, dst string) error {
 			return fmt.Errorf("failed to get the tar info header: %w", err)
 		}
 		header.Name = relPath
+		normalizeTarHeader(header)
 
 		if err := tw.WriteHeader(header); err != nil {
 			return fmt.Errorf("failed to set the write header: %w", err)
@@ -128,3 +287,26 @@ func createTarPackage(src, dst string) error {
 
 	return nil
 }
+
+// normalizeTarHeader removes all information from header that depends on the machine or time
+// the package is created on, so that the same input always produces the same package.
+func normalizeTarHeader(header *tar.Header) {
+	header.ModTime = time.Unix(0, 0)
+	header.AccessTime = time.Time{}
+	header.ChangeTime = time.Time{}
+	header.Uid = 0
+	header.Gid = 0
+	header.Uname = ""
+	header.Gname = ""
+
+	if header.Typeflag == tar.TypeDir {
+		header.Mode = 0o755
+		return
+	}
+
+	if header.Mode&0o111 != 0 {
+		header.Mode = 0o755
+	} else {
+		header.Mode = 0o644
+	}
+}
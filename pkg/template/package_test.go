@@ -0,0 +1,241 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeChart writes a chart with the given Chart.yaml. If templatesFile is true, templates is a file instead of
+// a directory, which is a lint warning.
+func writeChart(t *testing.T, chartYaml string, templatesFile bool) string {
+	t.Helper()
+
+	dir := filepath.Join(t.TempDir(), "mychart")
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte(chartYaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "values.yaml"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	templatesDir := filepath.Join(dir, "templates")
+	if templatesFile {
+		if err := os.WriteFile(templatesDir, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	} else {
+		if err := os.Mkdir(templatesDir, 0o750); err != nil {
+			t.Fatal(err)
+		}
+		configMap := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\n"
+		if err := os.WriteFile(filepath.Join(templatesDir, "configmap.yaml"), []byte(configMap), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestLintHelmChart(t *testing.T) {
+	const validChartYaml = "apiVersion: v2\nname: mychart\nversion: v1\nicon: https://example.com/icon.png\n"
+
+	tests := []struct {
+		name          string
+		chartYaml     string
+		templatesFile bool
+		strict        bool
+		wantErr       bool
+	}{
+		{name: "valid", chartYaml: validChartYaml},
+		{name: "valid strict", chartYaml: validChartYaml, strict: true},
+		{name: "warning", chartYaml: validChartYaml, templatesFile: true},
+		{name: "warning strict", chartYaml: validChartYaml, templatesFile: true, strict: true, wantErr: true},
+		{name: "error", chartYaml: "apiVersion: v2\nname: mychart\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeChart(t, tt.chartYaml, tt.templatesFile)
+
+			err := lintHelmChart(context.Background(), dir, tt.strict)
+			if tt.wantErr && err == nil {
+				t.Error("expected an error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCreateTarPackageReproducible(t *testing.T) {
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "addon", "templates"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		filepath.Join("addon", "Chart.yaml"):                  "apiVersion: v2\nname: addon\nversion: v1\n",
+		filepath.Join("addon", "templates", "configmap.yaml"): "apiVersion: v1\nkind: ConfigMap\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, compression := range []Compression{{Algorithm: CompressionGzip}, {Algorithm: CompressionZstd}} {
+		t.Run(compression.Algorithm, func(t *testing.T) {
+			dst := t.TempDir()
+
+			first := filepath.Join(dst, "first"+compression.FileExtension())
+			if err := createTarPackage(src, first, compression); err != nil {
+				t.Fatalf("failed to create package: %v", err)
+			}
+
+			// Modification times and permissions differ between checkouts and machines.
+			for name := range files {
+				path := filepath.Join(src, name)
+				if err := os.Chtimes(path, time.Now(), time.Now().Add(time.Hour)); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chmod(path, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			second := filepath.Join(dst, "second"+compression.FileExtension())
+			if err := createTarPackage(src, second, compression); err != nil {
+				t.Fatalf("failed to create package: %v", err)
+			}
+
+			if firstSum, secondSum := fileSHA256(t, first), fileSHA256(t, second); firstSum != secondSum {
+				t.Errorf("packages of the same directory differ: sha256 %s and %s", firstSum, secondSum)
+			}
+		})
+	}
+}
+
+func fileSHA256(t *testing.T, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// copyTestCharts copies the charts of tests/charts into a temporary directory, so that dependencies can be
+// downloaded into their charts directory.
+func copyTestCharts(t *testing.T) string {
+	t.Helper()
+
+	src := filepath.Join("..", "..", "tests", "charts")
+	dst := t.TempDir()
+
+	if err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, relativePath), 0o750)
+		}
+		data, err := os.ReadFile(filepath.Clean(path))
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dst, relativePath), data, 0o600)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	return dst
+}
+
+// outdatedChartLock does not match the dependencies of the chart with-dependency.
+const outdatedChartLock = `dependencies:
+- name: dependency
+  repository: file://../dependency
+  version: v0
+digest: sha256:0000000000000000000000000000000000000000000000000000000000000000
+generated: "2024-01-01T00:00:00Z"
+`
+
+func TestCreateHelmPackageUpdateDependencies(t *testing.T) {
+	// Keep helm away from the repositories and registry logins of the user.
+	helmHome := t.TempDir()
+	t.Setenv("HELM_REPOSITORY_CONFIG", filepath.Join(helmHome, "repositories.yaml"))
+	t.Setenv("HELM_REPOSITORY_CACHE", filepath.Join(helmHome, "cache"))
+	t.Setenv("HELM_REGISTRY_CONFIG", filepath.Join(helmHome, "registry.json"))
+
+	tests := []struct {
+		name               string
+		updateDependencies bool
+		chartLock          string
+		wantErr            bool
+	}{
+		{name: "missing dependency", wantErr: true},
+		{name: "update dependencies", updateDependencies: true},
+		{name: "outdated Chart.lock", updateDependencies: true, chartLock: outdatedChartLock, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chartPath := filepath.Join(copyTestCharts(t), "with-dependency")
+			if tt.chartLock != "" {
+				if err := os.WriteFile(filepath.Join(chartPath, "Chart.lock"), []byte(tt.chartLock), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			dst := t.TempDir()
+
+			err := createHelmPackage(context.Background(), chartPath, dst, HelmOptions{UpdateDependencies: tt.updateDependencies})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if _, err := os.Stat(filepath.Join(chartPath, "charts", "dependency-v1.tgz")); err != nil {
+				t.Errorf("expected the dependency in the charts directory: %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(dst, "with-dependency-v1.tgz")); err != nil {
+				t.Errorf("expected the helm package: %v", err)
+			}
+		})
+	}
+}
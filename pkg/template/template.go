@@ -18,12 +18,19 @@ limitations under the License.
 package template
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 
 	csctlclusterstack "github.com/SovereignCloudStack/csctl/pkg/clusterstack"
+	"github.com/SovereignCloudStack/csctl/pkg/logging"
 	"github.com/valyala/fasttemplate"
+	"gopkg.in/yaml.v3"
 )
 
 // CustomWalkFunc is the type for the walk function.
@@ -40,7 +47,15 @@ func MyWalk(src, dst string, walkFn CustomWalkFunc, meta *csctlclusterstack.Meta
 	return nil
 }
 
-func visitFile(src, dst, path string, info os.FileInfo, meta *csctlclusterstack.MetaData) error {
+// templater replaces the placeholders in the files of a cluster stack.
+type templater struct {
+	values map[string]interface{}
+	// strict makes templating fail on placeholders without value.
+	strict bool
+	logger *slog.Logger
+}
+
+func (t templater) visitFile(src, dst, path string, info os.FileInfo, _ *csctlclusterstack.MetaData) error {
 	relativePath, err := filepath.Rel(src, path)
 	if err != nil {
 		return fmt.Errorf("failed to relate directory: %w", err)
@@ -59,25 +74,109 @@ func visitFile(src, dst, path string, info os.FileInfo, meta *csctlclusterstack.
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
+	// Binary files like packaged charts are copied as they are.
+	if isBinary(fileData) {
+		if err := os.WriteFile(destPath, fileData, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to write file: %w", err)
+		}
+		return nil
+	}
+
 	tmp, err := fasttemplate.NewTemplate(string(fileData), "<< ", " >>")
 	if err != nil {
 		return fmt.Errorf("failed to create new template: %w", err)
 	}
 
-	output := tmp.ExecuteString(map[string]interface{}{
-		".ClusterClassVersion": meta.Versions.ClusterStack,
-		".ClusterAddonVersion": meta.Versions.Components.ClusterAddon,
-		".NodeImageVersion":    meta.Versions.Components.NodeImage,
+	var unresolved []string
+	output := tmp.ExecuteFuncString(func(w io.Writer, tag string) (int, error) {
+		value, ok := t.values[tag]
+		if !ok {
+			unresolved = append(unresolved, tag)
+			return fmt.Fprintf(w, "<< %s >>", tag)
+		}
+		return fmt.Fprintf(w, "%v", value)
 	})
 
-	if err := os.WriteFile(destPath, []byte(output), os.ModePerm); err != nil {
+	if len(unresolved) > 0 {
+		if t.strict {
+			return fmt.Errorf("unresolved placeholders in %s: %s", path, strings.Join(unresolved, ", "))
+		}
+		t.logger.Warn("Unresolved placeholders", "path", path, "placeholders", strings.Join(unresolved, ", "))
+	}
+
+	if err := os.WriteFile(destPath, []byte(output), info.Mode().Perm()); err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
 
 	return nil
 }
 
+// binaryDetectionSize is the number of bytes checked by isBinary, the same as git uses.
+const binaryDetectionSize = 8000
+
+// isBinary returns true if data contains a null byte at the beginning. Text files never do.
+func isBinary(data []byte) bool {
+	if len(data) > binaryDetectionSize {
+		data = data[:binaryDetectionSize]
+	}
+	return bytes.IndexByte(data, 0) != -1
+}
+
 // GenerateOutputFromTemplate is used to generate the template with replaced values.
-func GenerateOutputFromTemplate(src, dst string, meta *csctlclusterstack.MetaData) error {
-	return MyWalk(src, dst, visitFile, meta)
+// The values are available in addition to the built-in ones, which take precedence. They are
+// referenced with a leading dot, e.g. << .myKey >>.
+// If strict is true, placeholders without value are an error instead of being left in the output.
+func GenerateOutputFromTemplate(ctx context.Context, src, dst string, config *csctlclusterstack.CsctlConfig, meta *csctlclusterstack.MetaData, values map[string]interface{}, strict bool) error {
+	t := templater{
+		values: map[string]interface{}{},
+		strict: strict,
+		logger: logging.FromContext(ctx),
+	}
+
+	for key, value := range values {
+		t.values["."+key] = value
+	}
+
+	builtins := map[string]interface{}{
+		".ClusterClassVersion": meta.Versions.ClusterStack,
+		".ClusterAddonVersion": meta.Versions.Components.ClusterAddon,
+		".NodeImageVersion":    meta.Versions.Components.NodeImage,
+		".KubernetesVersion":   config.Config.KubernetesVersion,
+		".Provider":            config.Config.Provider.Type,
+		".ClusterStackName":    config.Config.ClusterStackName,
+	}
+	for key, value := range builtins {
+		t.values[key] = value
+	}
+
+	return MyWalk(src, dst, t.visitFile, meta)
+}
+
+// ReadValuesFile reads a YAML file with additional values for templating. Nested keys are
+// joined with dots, so that "registry: {url: example.com}" is referenced as << .registry.url >>.
+func ReadValuesFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read values file: %w", err)
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal values file %s: %w", path, err)
+	}
+
+	values := map[string]interface{}{}
+	flattenValues("", raw, values)
+
+	return values, nil
+}
+
+func flattenValues(prefix string, raw, values map[string]interface{}) {
+	for key, value := range raw {
+		if nested, ok := value.(map[string]interface{}); ok {
+			flattenValues(prefix+key+".", nested, values)
+			continue
+		}
+		values[prefix+key] = value
+	}
 }
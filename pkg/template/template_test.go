@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SovereignCloudStack/csctl/pkg/clusterstack"
+)
+
+func TestGenerateOutputFromTemplateValues(t *testing.T) {
+	const clusterClass = "image: << .registry.url >>/<< .myKey >>:<< .ClusterClassVersion >>\nname: << .ClusterStackName >>\n"
+
+	config := &clusterstack.CsctlConfig{}
+	config.Config.ClusterStackName = "ferrol"
+	metadata := &clusterstack.MetaData{}
+	metadata.Versions.ClusterStack = "v2"
+
+	tests := []struct {
+		name    string
+		values  string
+		strict  bool
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "custom values",
+			values: "myKey: foo\nregistry:\n  url: registry.example.com\n",
+			strict: true,
+			want:   "image: registry.example.com/foo:v2\nname: ferrol\n",
+		},
+		{
+			name:   "built-in values take precedence",
+			values: "myKey: foo\nregistry:\n  url: registry.example.com\nClusterStackName: other\n",
+			strict: true,
+			want:   "image: registry.example.com/foo:v2\nname: ferrol\n",
+		},
+		{
+			name:    "missing key",
+			values:  "registry:\n  url: registry.example.com\n",
+			strict:  true,
+			wantErr: "unresolved placeholders",
+		},
+		{
+			name:   "missing key is kept without strict",
+			values: "registry:\n  url: registry.example.com\n",
+			want:   "image: registry.example.com/<< .myKey >>:v2\nname: ferrol\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valuesFile := filepath.Join(t.TempDir(), "values.yaml")
+			if err := os.WriteFile(valuesFile, []byte(tt.values), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			values, err := ReadValuesFile(valuesFile)
+			if err != nil {
+				t.Fatalf("ReadValuesFile() failed: %v", err)
+			}
+
+			src := t.TempDir()
+			if err := os.MkdirAll(filepath.Join(src, "cluster-class", "templates"), 0o750); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(src, "cluster-class", "templates", "cluster-class.yaml"), []byte(clusterClass), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			dst := t.TempDir()
+			err = GenerateOutputFromTemplate(context.Background(), src, dst, config, metadata, values, tt.strict)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), ".myKey") {
+					t.Fatalf("GenerateOutputFromTemplate() error = %v, want %q for .myKey", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GenerateOutputFromTemplate() failed: %v", err)
+			}
+
+			got, err := os.ReadFile(filepath.Join(dst, "cluster-class", "templates", "cluster-class.yaml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}